@@ -11,6 +11,8 @@ require (
 	github.com/onsi/ginkgo v1.14.2
 	github.com/onsi/gomega v1.10.3
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/prometheus/client_golang v1.9.0
+	github.com/prometheus/client_model v0.2.0
 	go.uber.org/multierr v1.6.0
 	go.uber.org/zap v1.16.0
 	k8s.io/api v0.19.7
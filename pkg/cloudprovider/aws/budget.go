@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/awslabs/karpenter/pkg/packing"
+)
+
+// hourlyCostOf estimates the hourly cost in USD of launching a node for each of the packings, priced for the most
+// expensive of each packing's instance types so that it bounds the cost whichever of them is launched. It returns an
+// error if none of a packing's instance types are priced.
+func hourlyCostOf(packings []*packing.Packing) (float64, error) {
+	cost := 0.0
+	for _, p := range packings {
+		price := 0.0
+		for _, instanceType := range p.InstanceTypes {
+			price = math.Max(price, instanceType.Price)
+		}
+		if price == 0 {
+			return 0, fmt.Errorf("none of the %d instance types for %d pod(s) are priced", len(p.InstanceTypes), len(p.Pods))
+		}
+		cost += price
+	}
+	return cost, nil
+}
+
+// isWithinBudget returns an error if the estimated hourly cost of the packings exceeds the budget, unless the budget
+// is zero
+func isWithinBudget(packings []*packing.Packing, maxHourlyCost float64) error {
+	if maxHourlyCost == 0 {
+		return nil
+	}
+	cost, err := hourlyCostOf(packings)
+	if err != nil {
+		return fmt.Errorf("estimating hourly cost for budget, %w", err)
+	}
+	if cost > maxHourlyCost {
+		return fmt.Errorf("workload exceeds budget, %d node(s) cost up to $%.4f per hour, more than the budget of $%.4f", len(packings), cost, maxHourlyCost)
+	}
+	return nil
+}
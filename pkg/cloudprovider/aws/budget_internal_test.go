@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/karpenter/pkg/packing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Budgeting Hourly Cost", func() {
+	pricedAt := func(prices ...float64) *packing.Packing {
+		instanceTypes := []*packing.Instance{}
+		for i, price := range prices {
+			instanceTypes = append(instanceTypes, &packing.Instance{
+				InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String(fmt.Sprintf("m5.size%d", i))},
+				Price:            price,
+			})
+		}
+		return &packing.Packing{InstanceTypes: instanceTypes}
+	}
+
+	It("should sum the most expensive instance type of each packing", func() {
+		Expect(hourlyCostOf([]*packing.Packing{pricedAt(0.1, 0.2), pricedAt(0.3, 0)})).Should(BeNumerically("~", 0.5))
+	})
+	It("should allow packings within the budget", func() {
+		Expect(isWithinBudget([]*packing.Packing{pricedAt(0.1, 0.2), pricedAt(0.3)}, 0.5)).Should(Succeed())
+	})
+	It("should reject packings that exceed the budget", func() {
+		err := isWithinBudget([]*packing.Packing{pricedAt(0.1, 0.2), pricedAt(0.3)}, 0.4)
+		Expect(err).Should(MatchError(ContainSubstring("workload exceeds budget")))
+	})
+	It("should reject packings without prices", func() {
+		Expect(isWithinBudget([]*packing.Packing{pricedAt(0.1), pricedAt(0)}, 10)).ShouldNot(Succeed())
+	})
+	It("should allow any packings without a budget", func() {
+		Expect(isWithinBudget([]*packing.Packing{pricedAt(0)}, 0)).Should(Succeed())
+	})
+})
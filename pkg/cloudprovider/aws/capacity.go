@@ -18,9 +18,11 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
 	"github.com/awslabs/karpenter/pkg/cloudprovider"
 	"github.com/awslabs/karpenter/pkg/packing"
+	"github.com/awslabs/karpenter/pkg/utils/functional"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 )
@@ -53,25 +55,39 @@ func (c *Capacity) Create(ctx context.Context, cloudProviderConstraints *cloudpr
 		return nil, fmt.Errorf("filtering instance types by constraints, %w", err)
 	}
 
-	// 4. Compute Packing given the pods and instance types
-	instancePackings := c.packer.Pack(ctx, constraints.Pods, zonalInstanceTypes, cloudProviderConstraints)
+	// 4. Compute Packing given the pods and the instance types' allocatable resources
+	instancePackings := c.packer.Pack(ctx, constraints.Pods, c.instanceTypeProvider.Allocatable(zonalInstanceTypes), cloudProviderConstraints)
 	zap.S().Debugf("Computed %d packing(s) for %d provisionable pod(s)", len(instancePackings), len(constraints.Pods))
-
-	launchTemplate, err := c.launchTemplateProvider.Get(ctx, c.spec.Cluster, constraints)
-	if err != nil {
-		return nil, fmt.Errorf("getting launch template, %w", err)
+	if err := isWithinBudget(instancePackings, constraints.MaxHourlyCost); err != nil {
+		return nil, err
 	}
 
-	// 5. Create Instances
+	// 5. Create Instances, launching each with the launch template of its architecture
 	var instanceIDs []*string
 	podsForInstance := make(map[string][]*v1.Pod)
+	instanceTypesForInstance := make(map[string][]*packing.Instance)
+	launchTemplates := map[string]*ec2.LaunchTemplate{}
 	for _, packing := range instancePackings {
-		instanceID, err := c.instanceProvider.Create(ctx, launchTemplate, packing.InstanceTypes, zonalSubnetOptions, constraints.GetCapacityType())
+		architecture, instanceTypes := architectureFor(architecturesFor(constraints), packing.InstanceTypes)
+		capacityType, instanceTypes := capacityTypeFor(capacityTypesFor(constraints), instanceTypes)
+		launchTemplate, ok := launchTemplates[architecture]
+		if !ok {
+			architectureConstraints := constraints
+			if architecture != "" {
+				architectureConstraints.Architecture = &architecture
+			}
+			if launchTemplate, err = c.launchTemplateProvider.Get(ctx, c.spec.Cluster, architectureConstraints); err != nil {
+				return nil, fmt.Errorf("getting launch template, %w", err)
+			}
+			launchTemplates[architecture] = launchTemplate
+		}
+		instanceID, err := c.instanceProvider.Create(ctx, launchTemplate, instanceTypes, zonalSubnetOptions, capacityType)
 		if err != nil {
 			// TODO Aggregate errors and continue
 			return nil, fmt.Errorf("creating capacity %w", err)
 		}
 		podsForInstance[*instanceID] = packing.Pods
+		instanceTypesForInstance[*instanceID] = packing.InstanceTypes
 		instanceIDs = append(instanceIDs, instanceID)
 	}
 
@@ -82,7 +98,7 @@ func (c *Capacity) Create(ctx context.Context, cloudProviderConstraints *cloudpr
 	}
 	nodePackings := []cloudprovider.Packing{}
 	for instanceID, node := range nodes {
-		node.Labels = constraints.Labels
+		node.Labels = functional.UnionStringMaps(instanceTypeLabelsFor(node, instanceTypesForInstance[instanceID]), constraints.Labels)
 		node.Spec.Taints = constraints.Taints
 		nodePackings = append(nodePackings, cloudprovider.Packing{
 			Node: node,
@@ -92,6 +108,61 @@ func (c *Capacity) Create(ctx context.Context, cloudProviderConstraints *cloudpr
 	return nodePackings, nil
 }
 
+// capacityTypesFor returns the capacity types nodes may be launched as in order of preference, which end with
+// on-demand if instance types may have been selected for it as a fallback
+func capacityTypesFor(constraints Constraints) []string {
+	capacityTypes := constraints.GetCapacityTypes()
+	if constraints.AllowOnDemandFallback && !functional.ContainsString(capacityTypes, capacityTypeOnDemand) {
+		capacityTypes = append(append([]string{}, capacityTypes...), capacityTypeOnDemand)
+	}
+	return capacityTypes
+}
+
+// capacityTypeFor returns the most preferred capacity type that any of the instance types support,
+// along with the instance types that support it
+func capacityTypeFor(capacityTypes []string, instanceTypeOptions []*packing.Instance) (string, []*packing.Instance) {
+	for _, capacityType := range capacityTypes {
+		supported := []*packing.Instance{}
+		for _, instanceType := range instanceTypeOptions {
+			if functional.ContainsString(instanceType.CapacityTypes, capacityType) {
+				supported = append(supported, instanceType)
+			}
+		}
+		if len(supported) > 0 {
+			return capacityType, supported
+		}
+	}
+	return capacityTypes[0], instanceTypeOptions
+}
+
+// architectureFor returns the most preferred of the architectures that any of the instance types are tagged with,
+// along with the instance types tagged with it, or the empty architecture and all of the instance types if none are
+func architectureFor(architectures []string, instanceTypeOptions []*packing.Instance) (string, []*packing.Instance) {
+	for _, architecture := range architectures {
+		supported := []*packing.Instance{}
+		for _, instanceType := range instanceTypeOptions {
+			if instanceType.Architecture == architecture {
+				supported = append(supported, instanceType)
+			}
+		}
+		if len(supported) > 0 {
+			return architecture, supported
+		}
+	}
+	return "", instanceTypeOptions
+}
+
+// instanceTypeLabelsFor returns the node's labels, e.g. its instance type, along with the labels of the instance
+// type that was launched for the node
+func instanceTypeLabelsFor(node *v1.Node, instanceTypeOptions []*packing.Instance) map[string]string {
+	for _, instanceType := range instanceTypeOptions {
+		if *instanceType.InstanceType == node.Labels[v1alpha1.InstanceTypeLabelKey] {
+			return functional.UnionStringMaps(node.Labels, instanceType.Labels())
+		}
+	}
+	return functional.UnionStringMaps(node.Labels)
+}
+
 func (c *Capacity) Delete(ctx context.Context, nodes []*v1.Node) error {
 	return c.instanceProvider.Terminate(ctx, nodes)
 }
@@ -0,0 +1,47 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
+	"github.com/awslabs/karpenter/pkg/packing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Labeling Nodes", func() {
+	node := func() *v1.Node {
+		return &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{v1alpha1.InstanceTypeLabelKey: "m5.large"}}}
+	}
+	instanceTypeOptions := []*packing.Instance{
+		{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String("c5.large")}},
+		{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String("m5.large")}},
+	}
+
+	It("should keep the instance type along with the labels of the launched instance type", func() {
+		labels := instanceTypeLabelsFor(node(), instanceTypeOptions)
+		Expect(labels).Should(HaveKeyWithValue(v1alpha1.InstanceTypeLabelKey, "m5.large"))
+		Expect(labels).Should(HaveKeyWithValue(packing.InstanceFamilyLabelKey, "m5"))
+		Expect(labels).Should(HaveKeyWithValue(packing.InstanceSizeLabelKey, "large"))
+	})
+	It("should keep the instance type if it wasn't one of the options", func() {
+		labels := instanceTypeLabelsFor(node(), instanceTypeOptions[:1])
+		Expect(labels).Should(Equal(map[string]string{v1alpha1.InstanceTypeLabelKey: "m5.large"}))
+	})
+})
@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/karpenter/pkg/packing"
+	"github.com/awslabs/karpenter/pkg/utils/functional"
+)
+
+// CapacitySignal reports how often launches of instance types have failed for
+// lack of capacity, e.g. from CloudWatch metrics or launch history. It is
+// consulted for every selected instance type, so implementations should serve
+// the rates from memory and refresh them out of band.
+type CapacitySignal interface {
+	// LaunchFailureRate returns the fraction of recent launches of the
+	// instance type in the zone that failed, from 0 to 1
+	LaunchFailureRate(instanceType string, zone string) float64
+}
+
+// LaunchLatencySignal reports how long instance types take to launch and join
+// the cluster. Like CapacitySignal, it should serve latencies from memory.
+type LaunchLatencySignal interface {
+	// LaunchLatency returns the typical time from launching the instance type
+	// until its node is ready, or zero if unknown
+	LaunchLatency(instanceType string) time.Duration
+}
+
+// SpotAvailabilitySignal reports the zones that instance types are offered as
+// spot in, e.g. from the spot price history or fleet errors, which
+// DescribeInstanceTypeOfferings doesn't distinguish from on-demand.
+type SpotAvailabilitySignal interface {
+	// SpotZones returns the zones the instance type is offered as spot in, or
+	// nil if unknown
+	SpotZones(instanceType string) []string
+}
+
+// maxLaunchLatencyFraction is the fraction of a node's rotation interval it
+// may spend launching before its instance type is ranked as slow
+const maxLaunchLatencyFraction = 0.01
+
+// noCapacitySignal is the default signal, which reports no failures or latencies
+type noCapacitySignal struct{}
+
+func (noCapacitySignal) LaunchFailureRate(string, string) float64 {
+	return 0
+}
+
+func (noCapacitySignal) LaunchLatency(string) time.Duration {
+	return 0
+}
+
+func (noCapacitySignal) SpotZones(string) []string {
+	return nil
+}
+
+// withZonalCapacityTypes records the capacity types each instance type is offered as in each of its zones,
+// if the signal knows which of them offer it as spot
+func withZonalCapacityTypes(signal SpotAvailabilitySignal, instanceTypes []*packing.Instance) {
+	for _, instanceType := range instanceTypes {
+		spotZones := signal.SpotZones(*instanceType.InstanceType)
+		if spotZones == nil {
+			continue
+		}
+		instanceType.ZonalCapacityTypes = map[string][]string{}
+		for _, zone := range instanceType.Zones {
+			capacityTypes := []string{}
+			for _, capacityType := range aws.StringValueSlice(instanceType.SupportedUsageClasses) {
+				if capacityType != capacityTypeSpot || functional.ContainsString(spotZones, zone) {
+					capacityTypes = append(capacityTypes, capacityType)
+				}
+			}
+			instanceType.ZonalCapacityTypes[zone] = capacityTypes
+		}
+	}
+}
+
+// launchFailureRateOf returns the lowest failure rate of the instance type's zones that are eligible
+func launchFailureRateOf(signal CapacitySignal, instance *packing.Instance, zones []string) float64 {
+	rate := 1.0
+	for _, zone := range instance.Zones {
+		if len(zones) != 0 && !functional.ContainsString(zones, zone) {
+			continue
+		}
+		if zoneRate := signal.LaunchFailureRate(*instance.InstanceType, zone); zoneRate < rate {
+			rate = zoneRate
+		}
+	}
+	return rate
+}
+
+// rankByLaunchFailureRate ranks instance types by their launch failure rate, otherwise preserving order
+func rankByLaunchFailureRate(signal CapacitySignal, instanceTypes []*packing.Instance, zones []string) []*packing.Instance {
+	return rankBy(instanceTypes, func(instance *packing.Instance) float64 {
+		return launchFailureRateOf(signal, instance, zones)
+	})
+}
+
+// rankByLaunchLatency ranks instance types that launch quickly relative to the rotation
+// interval before those that don't, otherwise preserving order
+func rankByLaunchLatency(signal LaunchLatencySignal, instanceTypes []*packing.Instance, rotationInterval time.Duration) []*packing.Instance {
+	if rotationInterval == 0 {
+		return instanceTypes
+	}
+	return rankBy(instanceTypes, func(instance *packing.Instance) float64 {
+		return unless(signal.LaunchLatency(*instance.InstanceType) <= time.Duration(float64(rotationInterval)*maxLaunchLatencyFraction))
+	})
+}
@@ -0,0 +1,211 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/karpenter/pkg/packing"
+)
+
+// CatalogVersion identifies the schema of exported catalogs. It changes if
+// fields are renamed or removed, but not when fields are added.
+const CatalogVersion = "v1"
+
+// Catalog is every discovered instance type, sorted by name
+type Catalog struct {
+	Version       string         `json:"version"`
+	InstanceTypes []CatalogEntry `json:"instanceTypes"`
+}
+
+// CatalogEntry describes an instance type. Zones and capacity types are sorted.
+// Memory is in MiB and instance storage is in GB, as reported by EC2.
+type CatalogEntry struct {
+	Name                      string               `json:"name"`
+	Family                    string               `json:"family"`
+	Size                      string               `json:"size"`
+	Architectures             []string             `json:"architectures"`
+	VCPUs                     int64                `json:"vcpus"`
+	MemoryMiB                 int64                `json:"memoryMiB"`
+	Accelerators              []CatalogAccelerator `json:"accelerators,omitempty"`
+	Zones                     []string             `json:"zones"`
+	CapacityTypes             []string             `json:"capacityTypes"`
+	Hypervisor                string               `json:"hypervisor,omitempty"`
+	BareMetal                 bool                 `json:"bareMetal"`
+	CurrentGeneration         bool                 `json:"currentGeneration"`
+	NetworkPerformance        string               `json:"networkPerformance,omitempty"`
+	MaxNetworkInterfaces      int64                `json:"maxNetworkInterfaces,omitempty"`
+	IPv4AddressesPerInterface int64                `json:"ipv4AddressesPerInterface,omitempty"`
+	InstanceStorageGB         int64                `json:"instanceStorageGB,omitempty"`
+	EBSEncryptionSupported    bool                 `json:"ebsEncryptionSupported"`
+}
+
+// CatalogAccelerator is a kind of GPU, FPGA or inference accelerator attached to an instance type
+type CatalogAccelerator struct {
+	Type         string `json:"type"`
+	Manufacturer string `json:"manufacturer"`
+	Name         string `json:"name"`
+	Count        int64  `json:"count"`
+}
+
+// ExportCatalog returns every discovered instance type as JSON, e.g. to snapshot what is
+// selected from for debugging or offline analysis. See Catalog for the schema.
+func (p *InstanceTypeProvider) ExportCatalog(ctx context.Context) ([]byte, error) {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	catalog := Catalog{Version: CatalogVersion, InstanceTypes: []CatalogEntry{}}
+	for _, instanceType := range supportedInstanceTypes {
+		catalog.InstanceTypes = append(catalog.InstanceTypes, catalogEntryFor(instanceType))
+	}
+	sort.Slice(catalog.InstanceTypes, func(i, j int) bool {
+		return catalog.InstanceTypes[i].Name < catalog.InstanceTypes[j].Name
+	})
+	output, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling catalog, %w", err)
+	}
+	return output, nil
+}
+
+// OfflineCatalog is every discovered instance type with the info and offerings that selection uses, for clusters
+// in isolated environments where the EC2 API isn't reachable. Unlike Catalog, which summarizes instance types for
+// people, it's exported by ExportOfflineCatalog from a connected environment and loaded by
+// NewOfflineInstanceTypeProvider, so it's refreshed by exporting it again.
+type OfflineCatalog struct {
+	Version       string                `json:"version"`
+	Region        string                `json:"region,omitempty"`
+	InstanceTypes []OfflineCatalogEntry `json:"instanceTypes"`
+}
+
+// OfflineCatalogEntry is an instance type as described by EC2, along with the zones it's offered in and, if known,
+// the capacity types it's offered as in each of them
+type OfflineCatalogEntry struct {
+	Info               *ec2.InstanceTypeInfo `json:"info"`
+	Zones              []string              `json:"zones"`
+	ZonalCapacityTypes map[string][]string   `json:"zonalCapacityTypes,omitempty"`
+}
+
+// ExportOfflineCatalog returns every discovered instance type as an OfflineCatalog in JSON, sorted by name
+func (p *InstanceTypeProvider) ExportOfflineCatalog(ctx context.Context) ([]byte, error) {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	catalog := OfflineCatalog{Version: CatalogVersion, Region: p.region, InstanceTypes: []OfflineCatalogEntry{}}
+	for _, instanceType := range wellFormed(supportedInstanceTypes) {
+		info := instanceType.InstanceTypeInfo
+		catalog.InstanceTypes = append(catalog.InstanceTypes, OfflineCatalogEntry{
+			Info:               &info,
+			Zones:              instanceType.Zones,
+			ZonalCapacityTypes: instanceType.ZonalCapacityTypes,
+		})
+	}
+	sort.Slice(catalog.InstanceTypes, func(i, j int) bool {
+		return *catalog.InstanceTypes[i].Info.InstanceType < *catalog.InstanceTypes[j].Info.InstanceType
+	})
+	output, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling offline catalog, %w", err)
+	}
+	return output, nil
+}
+
+// NewOfflineInstanceTypeProvider returns a provider that serves the instance types of an OfflineCatalog instead of
+// discovering them from EC2, selecting from them just as a provider of the catalog's region would
+func NewOfflineInstanceTypeProvider(data []byte) (*InstanceTypeProvider, error) {
+	catalog := OfflineCatalog{}
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("unmarshaling offline catalog, %w", err)
+	}
+	if catalog.Version != CatalogVersion {
+		return nil, fmt.Errorf("unsupported offline catalog version %q, expected %q", catalog.Version, CatalogVersion)
+	}
+	instanceTypes := []*packing.Instance{}
+	for i, entry := range catalog.InstanceTypes {
+		if entry.Info == nil || entry.Info.InstanceType == nil {
+			return nil, fmt.Errorf("offline catalog entry %d has no instance type", i)
+		}
+		instanceTypes = append(instanceTypes, &packing.Instance{
+			InstanceTypeInfo:   *entry.Info,
+			Zones:              entry.Zones,
+			ZonalCapacityTypes: entry.ZonalCapacityTypes,
+		})
+	}
+	p := NewStaticInstanceTypeProvider(instanceTypes)
+	p.region = catalog.Region
+	return p, nil
+}
+
+func catalogEntryFor(instance *packing.Instance) CatalogEntry {
+	entry := CatalogEntry{
+		Name:              aws.StringValue(instance.InstanceType),
+		Family:            familyOf(aws.StringValue(instance.InstanceType)),
+		Size:              sizeOf(aws.StringValue(instance.InstanceType)),
+		Architectures:     []string{},
+		Zones:             append([]string{}, instance.Zones...),
+		CapacityTypes:     aws.StringValueSlice(instance.SupportedUsageClasses),
+		Hypervisor:        aws.StringValue(instance.Hypervisor),
+		BareMetal:         aws.BoolValue(instance.BareMetal),
+		CurrentGeneration: aws.BoolValue(instance.CurrentGeneration),
+	}
+	if instance.ProcessorInfo != nil {
+		entry.Architectures = aws.StringValueSlice(instance.ProcessorInfo.SupportedArchitectures)
+	}
+	if instance.VCpuInfo != nil {
+		entry.VCPUs = aws.Int64Value(instance.VCpuInfo.DefaultVCpus)
+	}
+	if instance.MemoryInfo != nil {
+		entry.MemoryMiB = aws.Int64Value(instance.MemoryInfo.SizeInMiB)
+	}
+	if instance.GpuInfo != nil {
+		for _, gpu := range instance.GpuInfo.Gpus {
+			entry.Accelerators = append(entry.Accelerators, CatalogAccelerator{Type: "gpu",
+				Manufacturer: aws.StringValue(gpu.Manufacturer), Name: aws.StringValue(gpu.Name), Count: aws.Int64Value(gpu.Count)})
+		}
+	}
+	if instance.FpgaInfo != nil {
+		for _, fpga := range instance.FpgaInfo.Fpgas {
+			entry.Accelerators = append(entry.Accelerators, CatalogAccelerator{Type: "fpga",
+				Manufacturer: aws.StringValue(fpga.Manufacturer), Name: aws.StringValue(fpga.Name), Count: aws.Int64Value(fpga.Count)})
+		}
+	}
+	if instance.InferenceAcceleratorInfo != nil {
+		for _, accelerator := range instance.InferenceAcceleratorInfo.Accelerators {
+			entry.Accelerators = append(entry.Accelerators, CatalogAccelerator{Type: "inference",
+				Manufacturer: aws.StringValue(accelerator.Manufacturer), Name: aws.StringValue(accelerator.Name), Count: aws.Int64Value(accelerator.Count)})
+		}
+	}
+	if instance.NetworkInfo != nil {
+		entry.NetworkPerformance = aws.StringValue(instance.NetworkInfo.NetworkPerformance)
+		entry.MaxNetworkInterfaces = aws.Int64Value(instance.NetworkInfo.MaximumNetworkInterfaces)
+		entry.IPv4AddressesPerInterface = aws.Int64Value(instance.NetworkInfo.Ipv4AddressesPerInterface)
+	}
+	if instance.InstanceStorageInfo != nil {
+		entry.InstanceStorageGB = aws.Int64Value(instance.InstanceStorageInfo.TotalSizeInGB)
+	}
+	if instance.EbsInfo != nil {
+		entry.EBSEncryptionSupported = aws.StringValue(instance.EbsInfo.EncryptionSupport) == ec2.EbsEncryptionSupportSupported
+	}
+	sort.Strings(entry.Zones)
+	sort.Strings(entry.CapacityTypes)
+	return entry
+}
@@ -36,10 +36,19 @@ var (
 // Constraints are AWS specific constraints
 type Constraints cloudprovider.Constraints
 
+// GetCapacityType returns the most preferred capacity type
 func (c *Constraints) GetCapacityType() string {
+	return c.GetCapacityTypes()[0]
+}
+
+// GetCapacityTypes returns the capacity types in order of preference
+func (c *Constraints) GetCapacityTypes() []string {
+	if len(c.CapacityTypes) != 0 {
+		return c.CapacityTypes
+	}
 	capacityType, ok := c.Labels[capacityTypeLabel]
 	if !ok {
 		capacityType = capacityTypeOnDemand
 	}
-	return capacityType
+	return []string{capacityType}
 }
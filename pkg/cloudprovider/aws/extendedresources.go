@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/awslabs/karpenter/pkg/packing"
+	"github.com/awslabs/karpenter/pkg/utils/resources"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ExtendedResourceFunc is true if the instance type provides at least the quantity of an extended resource, e.g.
+// a device plugin's, that a single pod requests
+type ExtendedResourceFunc func(instance *packing.Instance, quantity int64) bool
+
+// extendedResource maps an extended resource to the instance types that provide it, along with the name that
+// instance types it eliminates are attributed to
+type extendedResource struct {
+	resource v1.ResourceName
+	name     string
+	provides ExtendedResourceFunc
+}
+
+// defaultExtendedResources are the accelerators that every provider knows how to find instance types for
+var defaultExtendedResources = []extendedResource{
+	{resource: resources.NvidiaGPU, name: "nvidiaGPU", provides: func(instance *packing.Instance, quantity int64) bool {
+		return packing.CountSchedulableNvidiaGPUs(instance) >= quantity
+	}},
+	{resource: resources.AMDGPU, name: "amdGPU", provides: func(instance *packing.Instance, quantity int64) bool {
+		return packing.CountAMDGPUs(instance) >= quantity
+	}},
+	{resource: resources.AWSNeuron, name: "awsNeuron", provides: func(instance *packing.Instance, quantity int64) bool {
+		return packing.CountAWSNeurons(instance) >= quantity
+	}},
+}
+
+// WithExtendedResource restricts pods that request the extended resource, e.g. vpc.amazonaws.com/efa, to instance
+// types that provide it, after the default accelerators. Instance types it eliminates are attributed to the name in
+// selection results and explanations. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithExtendedResource(resource v1.ResourceName, name string, provides ExtendedResourceFunc) *InstanceTypeProvider {
+	p.extendedResources = append(p.extendedResources, extendedResource{resource: resource, name: name, provides: provides})
+	return p
+}
+
+// extendedResourcePredicatesFor returns a predicate for each default and registered extended resource, in order,
+// which only restricts instance types if the resource is requested. Instance types must provide the largest
+// quantity that any one of the pods requests, since the pods may be packed onto separate nodes.
+func (p *InstanceTypeProvider) extendedResourcePredicatesFor(pods []*v1.Pod) []predicate {
+	predicates := []predicate{}
+	for _, e := range append(append([]extendedResource{}, defaultExtendedResources...), p.extendedResources...) {
+		extended := e
+		quantity, requested := largestRequestFor(pods, extended.resource)
+		predicates = append(predicates, predicate{name: extended.name, matches: func(instance *packing.Instance) bool {
+			return !requested || extended.provides(p.withGPUReplicas(instance), quantity)
+		}})
+	}
+	return predicates
+}
+
+// WithGPUTimeSlicing advertises each NVIDIA GPU of instance types in the families as the number of replicas, keyed
+// by family, e.g. {"g4dn": 4} for clusters whose device plugin shares each GPU by time-slicing among 4 pods. Pods'
+// GPU requests are compared to, and packed onto, the replicas rather than the physical GPUs. It returns the same
+// provider simply for ease of use.
+func (p *InstanceTypeProvider) WithGPUTimeSlicing(replicas map[string]int64) *InstanceTypeProvider {
+	p.gpuReplicas = replicas
+	return p
+}
+
+// withGPUReplicas returns a copy of the instance type with the replicas of its family's GPUs, or the same instance
+// type if its family's GPUs aren't shared by time-slicing
+func (p *InstanceTypeProvider) withGPUReplicas(instance *packing.Instance) *packing.Instance {
+	replicas, ok := p.gpuReplicas[familyOf(*instance.InstanceType)]
+	if !ok || instance.GPUReplicas == replicas {
+		return instance
+	}
+	copied := *instance
+	copied.GPUReplicas = replicas
+	return &copied
+}
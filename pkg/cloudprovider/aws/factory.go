@@ -13,6 +13,7 @@ limitations under the License.
 package aws
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -31,11 +32,25 @@ import (
 	"github.com/awslabs/karpenter/pkg/utils/log"
 	"github.com/awslabs/karpenter/pkg/utils/project"
 	"github.com/patrickmn/go-cache"
+	"go.uber.org/zap"
 )
 
 const (
 	// CacheTTL restricts QPS to AWS APIs to this interval for verifying setup resources.
 	CacheTTL = 5 * time.Minute
+	// InstanceTypeInfoCacheTTL restricts QPS to the EC2 DescribeInstanceTypes API, whose results
+	// only change when instance types launch, to this interval.
+	InstanceTypeInfoCacheTTL = 24 * time.Hour
+	// SelectionCacheTTL reuses the instance types selected for identical constraints for this interval, short
+	// enough that capacity and launch latency signals are reflected in the ranking soon after they change.
+	SelectionCacheTTL = 10 * time.Second
+	// PricingCacheTTL restricts QPS to pricing APIs to this interval.
+	PricingCacheTTL = 1 * time.Hour
+	// PricingErrorCacheTTL restricts retries of pricing APIs that failed to this interval.
+	PricingErrorCacheTTL = 1 * time.Minute
+	// WarmTimeout bounds discovering instance types in the background during initialization, after which
+	// they're discovered when first selected.
+	WarmTimeout = 2 * time.Minute
 	// CacheCleanupInterval triggers cache cleanup (lazy eviction) at this interval.
 	CacheCleanupInterval = 10 * time.Minute
 	// ClusterTagKeyFormat is set on all Kubernetes owned resources.
@@ -80,12 +95,21 @@ func NewFactory(options cloudprovider.Options) *Factory {
 		clientSet: options.ClientSet,
 	}
 
+	instanceTypeProvider := NewInstanceTypeProvider(ec2api)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), WarmTimeout)
+		defer cancel()
+		if err := instanceTypeProvider.Warm(ctx); err != nil {
+			zap.S().Errorf("Continuing without cached instance types, %s", err.Error())
+		}
+	}()
+
 	return &Factory{
 		vpcProvider:            vpcProvider,
 		nodeFactory:            &NodeFactory{ec2api: ec2api},
 		packer:                 packing.NewPacker(),
 		instanceProvider:       &InstanceProvider{ec2api: ec2api, vpc: vpcProvider},
-		instanceTypeProvider:   NewInstanceTypeProvider(ec2api),
+		instanceTypeProvider:   instanceTypeProvider,
 		launchTemplateProvider: launchTemplateProvider,
 	}
 }
@@ -17,9 +17,11 @@ package fake
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Pallinder/go-randomdata"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
@@ -28,17 +30,23 @@ import (
 // EC2Behavior must be reset between tests otherwise tests will
 // pollute each other.
 type EC2Behavior struct {
-	CreateFleetOutput                   *ec2.CreateFleetOutput
-	DescribeInstancesOutput             *ec2.DescribeInstancesOutput
-	DescribeLaunchTemplatesOutput       *ec2.DescribeLaunchTemplatesOutput
-	DescribeSubnetsOutput               *ec2.DescribeSubnetsOutput
-	DescribeSecurityGroupsOutput        *ec2.DescribeSecurityGroupsOutput
-	DescribeInstanceTypesOutput         *ec2.DescribeInstanceTypesOutput
-	DescribeInstanceTypeOfferingsOutput *ec2.DescribeInstanceTypeOfferingsOutput
-	DescribeAvailabilityZonesOutput     *ec2.DescribeAvailabilityZonesOutput
-	WantErr                             error
-	CalledWithCreateFleetInput          []ec2.CreateFleetInput
-	Instances                           []*ec2.Instance
+	CreateFleetOutput                            *ec2.CreateFleetOutput
+	DescribeInstancesOutput                      *ec2.DescribeInstancesOutput
+	DescribeLaunchTemplatesOutput                *ec2.DescribeLaunchTemplatesOutput
+	DescribeSubnetsOutput                        *ec2.DescribeSubnetsOutput
+	DescribeSecurityGroupsOutput                 *ec2.DescribeSecurityGroupsOutput
+	DescribeInstanceTypesOutput                  *ec2.DescribeInstanceTypesOutput
+	DescribeInstanceTypeOfferingsOutput          *ec2.DescribeInstanceTypeOfferingsOutput
+	DescribeRegionalInstanceTypeOfferingsOutput  *ec2.DescribeInstanceTypeOfferingsOutput
+	DescribeAvailabilityZonesOutput              *ec2.DescribeAvailabilityZonesOutput
+	WantErr                                      error
+	WantDescribeInstanceTypeOfferingsErr         error
+	ThrottledDescribeCalls                       int
+	DescribeDelay                                time.Duration
+	CalledWithCreateFleetInput                   []ec2.CreateFleetInput
+	CalledWithDescribeInstanceTypesInput         []ec2.DescribeInstanceTypesInput
+	CalledWithDescribeInstanceTypeOfferingsInput []ec2.DescribeInstanceTypeOfferingsInput
+	Instances                                    []*ec2.Instance
 }
 
 type EC2API struct {
@@ -52,6 +60,21 @@ func (e *EC2API) Reset() {
 	e.EC2Behavior = EC2Behavior{}
 }
 
+// delay simulates the latency of describe calls
+func (e *EC2API) delay() {
+	time.Sleep(e.DescribeDelay)
+}
+
+// throttle fails with RequestLimitExceeded until ThrottledDescribeCalls
+// describe calls have been throttled
+func (e *EC2API) throttle() error {
+	if e.ThrottledDescribeCalls > 0 {
+		e.ThrottledDescribeCalls--
+		return awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)
+	}
+	return nil
+}
+
 func (e *EC2API) CreateFleetWithContext(ctx context.Context, input *ec2.CreateFleetInput, options ...request.Option) (*ec2.CreateFleetOutput, error) {
 	e.CalledWithCreateFleetInput = append(e.CalledWithCreateFleetInput, *input)
 	if e.WantErr != nil {
@@ -132,9 +155,14 @@ func (e *EC2API) DescribeAvailabilityZonesWithContext(context.Context, *ec2.Desc
 }
 
 func (e *EC2API) DescribeInstanceTypesPagesWithContext(ctx context.Context, input *ec2.DescribeInstanceTypesInput, fn func(*ec2.DescribeInstanceTypesOutput, bool) bool, opts ...request.Option) error {
+	e.delay()
+	e.CalledWithDescribeInstanceTypesInput = append(e.CalledWithDescribeInstanceTypesInput, *input)
 	if e.WantErr != nil {
 		return e.WantErr
 	}
+	if err := e.throttle(); err != nil {
+		return err
+	}
 	if e.DescribeInstanceTypesOutput != nil {
 		fn(e.DescribeInstanceTypesOutput, false)
 		return nil
@@ -238,9 +266,23 @@ func (e *EC2API) DescribeInstanceTypesPagesWithContext(ctx context.Context, inpu
 }
 
 func (e *EC2API) DescribeInstanceTypeOfferingsPagesWithContext(ctx context.Context, input *ec2.DescribeInstanceTypeOfferingsInput, fn func(*ec2.DescribeInstanceTypeOfferingsOutput, bool) bool, opts ...request.Option) error {
+	e.delay()
+	e.CalledWithDescribeInstanceTypeOfferingsInput = append(e.CalledWithDescribeInstanceTypeOfferingsInput, *input)
 	if e.WantErr != nil {
 		return e.WantErr
 	}
+	if err := e.throttle(); err != nil {
+		return err
+	}
+	if e.WantDescribeInstanceTypeOfferingsErr != nil {
+		return e.WantDescribeInstanceTypeOfferingsErr
+	}
+	if aws.StringValue(input.LocationType) == ec2.LocationTypeRegion {
+		if e.DescribeRegionalInstanceTypeOfferingsOutput != nil {
+			fn(e.DescribeRegionalInstanceTypeOfferingsOutput, false)
+		}
+		return nil
+	}
 	if e.DescribeInstanceTypeOfferingsOutput != nil {
 		fn(e.DescribeInstanceTypeOfferingsOutput, false)
 		return nil
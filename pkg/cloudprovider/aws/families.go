@@ -0,0 +1,200 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/awslabs/karpenter/pkg/packing"
+)
+
+var (
+	// defaultFamilyPrefixes select instance types that are suited for general
+	// workloads when no instance types are constrained. Prefixes only match
+	// families of the same category, see hasAnyFamilyPrefix.
+	defaultFamilyPrefixes = []string{
+		"m", "c", "r", "a", // Standard
+		"t3", "t4", // Burstable
+		"p", "inf", "g", // Accelerators
+	}
+	// trunkENIFamilies support trunk network interfaces, which are not exposed
+	// by the EC2 API. https://docs.aws.amazon.com/eks/latest/userguide/security-groups-for-pods.html
+	trunkENIFamilies = []string{
+		"a1",
+		"c5", "c5a", "c5ad", "c5d", "c5n", "c6g", "c6gd", "c6gn", "c6i",
+		"d3", "d3en",
+		"g4ad", "g4dn",
+		"i3en",
+		"inf1",
+		"m5", "m5a", "m5ad", "m5d", "m5dn", "m5n", "m5zn", "m6g", "m6gd", "m6i",
+		"p3dn", "p4d",
+		"r5", "r5a", "r5ad", "r5b", "r5d", "r5dn", "r5n", "r6g", "r6gd", "r6i",
+		"x2gd",
+		"z1d",
+	}
+	// inTransitEncryptionFamilies are Nitro based families that encrypt traffic between instances in
+	// the same VPC, which is not exposed by the EC2 API. https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/data-protection.html
+	inTransitEncryptionFamilies = []string{
+		"c5a", "c5ad", "c5n", "c6a", "c6gn", "c6i", "c6id", "c6in", "c7g", "c7gn",
+		"d3", "d3en",
+		"g4ad", "g4dn", "g5",
+		"hpc6a",
+		"i3en", "i4i", "im4gn", "is4gen",
+		"inf1",
+		"m5dn", "m5n", "m5zn", "m6a", "m6i", "m6id", "m6idn", "m6in", "m7g",
+		"p3dn", "p4d",
+		"r5dn", "r5n", "r6a", "r6i", "r6id", "r6idn", "r6in", "r7g",
+		"x2idn", "x2iedn", "x2iezn",
+	}
+	// jumboFramePreviousGenerationFamilies are the previous generation families
+	// that support 9001 MTU, which all current generation families support.
+	// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/network_mtu.html
+	jumboFramePreviousGenerationFamilies = []string{
+		"c3", "c4", "cc2", "cr1", "d2", "g2", "g3", "hi1", "hs1", "i2", "i3", "m3", "m4", "p2", "p3", "r3", "r4", "t2", "x1", "x1e",
+	}
+	// microarchitectures maps families to their processor microarchitecture,
+	// which is not exposed by the EC2 API. Families that span more than one
+	// microarchitecture are mapped to the oldest.
+	microarchitectures = map[string]string{
+		"a1": "graviton", "c6g": "graviton2", "c6gd": "graviton2", "c6gn": "graviton2", "g5g": "graviton2", "im4gn": "graviton2",
+		"is4gen": "graviton2", "m6g": "graviton2", "m6gd": "graviton2", "r6g": "graviton2", "r6gd": "graviton2",
+		"t4g": "graviton2", "x2gd": "graviton2",
+		"c7g": "graviton3", "c7gd": "graviton3", "c7gn": "graviton3", "m7g": "graviton3", "m7gd": "graviton3",
+		"r7g": "graviton3", "r7gd": "graviton3",
+		"c8g": "graviton4", "m8g": "graviton4", "r8g": "graviton4", "x8g": "graviton4",
+		"c4": "haswell",
+		"m4": "broadwell", "p3": "broadwell", "r4": "broadwell", "x1": "broadwell", "x1e": "broadwell",
+		"c5": "skylake", "c5d": "skylake", "m5": "skylake", "m5d": "skylake", "r5": "skylake", "r5d": "skylake",
+		"c5n": "skylake", "i3en": "skylake", "p3dn": "skylake", "t3": "skylake", "z1d": "skylake",
+		"g4dn": "cascade-lake", "inf1": "cascade-lake", "m5dn": "cascade-lake", "m5n": "cascade-lake",
+		"m5zn": "cascade-lake", "p4d": "cascade-lake", "r5b": "cascade-lake", "r5dn": "cascade-lake", "r5n": "cascade-lake",
+		"c6i": "ice-lake", "c6id": "ice-lake", "c6in": "ice-lake", "i4i": "ice-lake", "m6i": "ice-lake",
+		"m6id": "ice-lake", "m6idn": "ice-lake", "m6in": "ice-lake", "r6i": "ice-lake", "r6id": "ice-lake",
+		"r6idn": "ice-lake", "r6in": "ice-lake", "x2idn": "ice-lake", "x2iedn": "ice-lake",
+		"c7i": "sapphire-rapids", "m7i": "sapphire-rapids", "m7i-flex": "sapphire-rapids",
+		"r7i": "sapphire-rapids", "r7iz": "sapphire-rapids",
+		"m5a": "zen", "m5ad": "zen", "r5a": "zen", "r5ad": "zen", "t3a": "zen",
+		"c5a": "zen2", "c5ad": "zen2", "g4ad": "zen2",
+		"c6a": "zen3", "hpc6a": "zen3", "m6a": "zen3", "r6a": "zen3",
+		"c7a": "zen4", "m7a": "zen4", "r7a": "zen4",
+	}
+)
+
+// WithRegionalDefaultFamilies overrides the family prefixes selected when no instance types are constrained, keyed
+// by region, e.g. {"us-west-1": {"m", "c", "r"}} to exclude accelerator families in regions that lack them. Only the
+// prefixes of the provider's region apply, so the same overrides can be passed to the providers of every region. It
+// returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithRegionalDefaultFamilies(prefixes map[string][]string) *InstanceTypeProvider {
+	p.regionalDefaultFamilies = prefixes
+	return p
+}
+
+// defaultFamilyPrefixesFor returns the family prefixes of the workload preset,
+// or of the provider's region if there's no preset, narrowed to those matching
+// at least one of the discovered instance types
+func (p *InstanceTypeProvider) defaultFamilyPrefixesFor(presetName string, instanceTypes []*packing.Instance) []string {
+	prefixes, ok := p.regionalDefaultFamilies[p.region]
+	if !ok {
+		prefixes = defaultFamilyPrefixes
+	}
+	if workloadPreset, ok := presets[presetName]; ok {
+		prefixes = workloadPreset.familyPrefixes
+	}
+	offered := []string{}
+	for _, prefix := range prefixes {
+		for _, instanceType := range instanceTypes {
+			if hasAnyFamilyPrefix(*instanceType.InstanceType, prefix) {
+				offered = append(offered, prefix)
+				break
+			}
+		}
+	}
+	return offered
+}
+
+// hasAnyFamilyPrefix returns true if the instance type's family starts with any of the prefixes within the same
+// category, e.g. m matches m5.large and m6gd.large, but not mac1.metal, and i matches i3en.large, but not inf1.xlarge
+func hasAnyFamilyPrefix(instanceType string, prefixes ...string) bool {
+	family := familyOf(instanceType)
+	category := parseFamily(family).category
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(family, prefix) && parseFamily(prefix).category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// familyOf returns the family of an instance type, e.g. m5 for m5.large
+func familyOf(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// sizeOf returns the size of an instance type, e.g. large for m5.large
+func sizeOf(instanceType string) string {
+	parts := strings.SplitN(instanceType, ".", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// instanceFamily is the parsed form of a family name. For example, m6gd has
+// category m, generation 6, processor g (Graviton) and attributes d.
+type instanceFamily struct {
+	category   string
+	generation int
+	processor  string
+	attributes string
+}
+
+// parseFamily parses a family name. Processors are a (AMD), g (Graviton) and
+// i (Intel); families without a processor letter are typically Intel.
+func parseFamily(family string) instanceFamily {
+	i := strings.IndexFunc(family, unicode.IsDigit)
+	if i < 0 {
+		return instanceFamily{category: family}
+	}
+	parsed := instanceFamily{category: family[:i]}
+	j := i
+	for j < len(family) && unicode.IsDigit(rune(family[j])) {
+		j++
+	}
+	parsed.generation, _ = strconv.Atoi(family[i:j])
+	suffix := family[j:]
+	if len(suffix) > 0 && strings.ContainsAny(suffix[:1], "agi") {
+		parsed.processor, suffix = suffix[:1], suffix[1:]
+	}
+	parsed.attributes = suffix
+	return parsed
+}
+
+// GenerationOf returns the generation of an instance type's family, e.g. 6 for m6i.large, m6g.large or m6.large,
+// and 4 for the previous generation m4.large, or zero if the family has no generation
+func GenerationOf(instanceType string) int {
+	return parseFamily(familyOf(instanceType)).generation
+}
+
+// RankByGeneration orders instance types from the newest generation to the oldest, otherwise preserving order, e.g.
+// for the packer or launcher to break ties between equally priced instance types in favor of newer hardware
+func RankByGeneration(instanceTypes []*packing.Instance) []*packing.Instance {
+	sort.SliceStable(instanceTypes, func(i, j int) bool {
+		return GenerationOf(*instanceTypes[i].InstanceType) > GenerationOf(*instanceTypes[j].InstanceType)
+	})
+	return instanceTypes
+}
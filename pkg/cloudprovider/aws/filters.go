@@ -0,0 +1,109 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/awslabs/karpenter/pkg/packing"
+)
+
+// InstanceTypeFilter is a check that instance types must satisfy to be
+// selected, e.g. to only select instance types that are approved for a
+// company's images. The built-in constraints are filters that every provider
+// registers by default, ahead of those registered with WithFilter.
+type InstanceTypeFilter interface {
+	// Matches is true if the instance type may be selected for the
+	// constraints in the zones, which are empty if unconstrained
+	Matches(instance *packing.Instance, constraints Constraints, zones []string) bool
+}
+
+// InstanceTypeFilterFunc adapts a function to an InstanceTypeFilter
+type InstanceTypeFilterFunc func(instance *packing.Instance, constraints Constraints, zones []string) bool
+
+func (f InstanceTypeFilterFunc) Matches(instance *packing.Instance, constraints Constraints, zones []string) bool {
+	return f(instance, constraints, zones)
+}
+
+// preparedFilter is a filter that computes what it checks once per selection, e.g. from the pods' requests or the
+// candidate instance types, rather than for each instance type
+type preparedFilter interface {
+	InstanceTypeFilter
+	// prepare returns the check for the constraints in the zones, given every candidate instance type
+	prepare(constraints Constraints, zones []string, instanceTypes []*packing.Instance) func(*packing.Instance) bool
+}
+
+// preparedFilterFunc adapts a function to a preparedFilter. Checked on its own, an instance type is the only candidate.
+type preparedFilterFunc func(constraints Constraints, zones []string, instanceTypes []*packing.Instance) func(*packing.Instance) bool
+
+func (f preparedFilterFunc) Matches(instance *packing.Instance, constraints Constraints, zones []string) bool {
+	return f(constraints, zones, []*packing.Instance{instance})(instance)
+}
+
+func (f preparedFilterFunc) prepare(constraints Constraints, zones []string, instanceTypes []*packing.Instance) func(*packing.Instance) bool {
+	return f(constraints, zones, instanceTypes)
+}
+
+// namedFilter is a filter along with the name that instance types it eliminates are attributed to
+type namedFilter struct {
+	name   string
+	filter InstanceTypeFilter
+	// builtIn filters are registered by default and checked before extended resources and constraint groups
+	builtIn bool
+}
+
+// withDefaultFilters registers the built-in filters ahead of any others
+func (p *InstanceTypeProvider) withDefaultFilters() *InstanceTypeProvider {
+	for _, f := range p.defaultFilters() {
+		f.builtIn = true
+		p.filters = append(p.filters, f)
+	}
+	return p
+}
+
+// WithFilter registers a filter that instance types must satisfy after the built-in ones, including those of
+// constraint groups. Instance types it eliminates are attributed to its name in selection results and explanations.
+// Filters are applied in the order they're registered. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithFilter(name string, filter InstanceTypeFilter) *InstanceTypeProvider {
+	p.filters = append(p.filters, namedFilter{name: name, filter: filter})
+	return p
+}
+
+// filterNames returns the names of the registered filters, in order
+func (p *InstanceTypeProvider) filterNames() []string {
+	names := []string{}
+	for _, f := range p.filters {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// filterPredicatesFor returns a predicate for each of the built-in, or else the additional, registered filters in
+// order, preparing those that check the candidate instance types as a whole
+func (p *InstanceTypeProvider) filterPredicatesFor(constraints Constraints, zones []string, instanceTypes []*packing.Instance, builtIn bool) []predicate {
+	predicates := []predicate{}
+	for _, f := range p.filters {
+		if f.builtIn != builtIn {
+			continue
+		}
+		if prepared, ok := f.filter.(preparedFilter); ok {
+			predicates = append(predicates, predicate{name: f.name, matches: prepared.prepare(constraints, zones, instanceTypes)})
+			continue
+		}
+		filter := f.filter
+		predicates = append(predicates, predicate{name: f.name, matches: func(instance *packing.Instance) bool {
+			return filter.Matches(instance, constraints, zones)
+		}})
+	}
+	return predicates
+}
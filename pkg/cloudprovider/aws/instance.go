@@ -64,7 +64,7 @@ func (p *InstanceProvider) Create(ctx context.Context,
 	for i, instanceType := range instanceTypeOptions {
 		for _, zone := range instanceType.Zones {
 			subnets := zonalSubnetOptions[zone]
-			if len(subnets) == 0 {
+			if len(subnets) == 0 || !instanceType.IsOfferedIn(zone, capacityType) {
 				continue
 			}
 			override := &ec2.FleetLaunchTemplateOverridesRequest{
@@ -16,11 +16,23 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
+	"github.com/awslabs/karpenter/pkg/cloudprovider"
 	"github.com/awslabs/karpenter/pkg/cloudprovider/aws/utils"
 	"github.com/awslabs/karpenter/pkg/packing"
 	"github.com/awslabs/karpenter/pkg/utils/functional"
@@ -31,100 +43,883 @@ import (
 )
 
 const (
-	allInstanceTypesKey = "all"
+	allInstanceTypesKey               = "all"
+	currentGenerationInstanceTypesKey = "current-generation"
+	instanceTypeInfoKeyPrefix         = "info/"
+	offeringsKey                      = "offerings"
+	selectionKeyPrefix                = "selection/"
 )
 
+// authorizationErrorCodes are returned by AWS APIs if the caller isn't permitted to call them
+var authorizationErrorCodes = []string{"UnauthorizedOperation", "AccessDenied", "AccessDeniedException", "AuthFailure"}
+
 type InstanceTypeProvider struct {
 	ec2api ec2iface.EC2API
 	cache  *cache.Cache
+	region string
+	// version is incremented each time the discovered instance types are refreshed
+	version             uint64
+	capacitySignal      CapacitySignal
+	launchLatencySignal LaunchLatencySignal
+	spotAvailability    SpotAvailabilitySignal
+	systemReserved      Reserved
+	kubeReserved        Reserved
+	instanceTypeInfoTTL time.Duration
+	offeringsTTL        time.Duration
+	priceSource         PriceSource
+	interruptionSource  InterruptionSource
+	describeBackoff     Backoff
+	discoveries         singleFlight
+	pricings            singleFlight
+	prefixDelegation    bool
+	filters             []namedFilter
+	extendedResources   []extendedResource
+	gpuReplicas         map[string]int64
+	scorer              InstanceTypeScorer
+	regionalOfferings   bool
+	// regionalDefaultFamilies overrides defaultFamilyPrefixes, keyed by region
+	regionalDefaultFamilies map[string][]string
 }
 
+// NewInstanceTypeProvider returns a provider of the instance types in the region the EC2 client is configured for.
+// Providers don't share caches, so controllers that manage nodes in several regions use a provider per region.
 func NewInstanceTypeProvider(ec2api ec2iface.EC2API) *InstanceTypeProvider {
-	return &InstanceTypeProvider{
-		ec2api: ec2api,
-		cache:  cache.New(CacheTTL, CacheCleanupInterval),
+	return (&InstanceTypeProvider{
+		ec2api:              ec2api,
+		cache:               cache.New(CacheTTL, CacheCleanupInterval),
+		region:              regionOf(ec2api),
+		capacitySignal:      noCapacitySignal{},
+		launchLatencySignal: noCapacitySignal{},
+		spotAvailability:    noCapacitySignal{},
+		instanceTypeInfoTTL: InstanceTypeInfoCacheTTL,
+		offeringsTTL:        CacheTTL,
+		describeBackoff:     DefaultDescribeBackoff,
+	}).withDefaultFilters()
+}
+
+// WithCapacitySignal ranks instance types by the signal's launch failure rates, and enables the
+// MaxLaunchFailureRate constraint. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithCapacitySignal(signal CapacitySignal) *InstanceTypeProvider {
+	p.capacitySignal = signal
+	return p
+}
+
+// WithLaunchLatencySignal ranks instance types that launch slowly relative to the RotationInterval
+// constraint last. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithLaunchLatencySignal(signal LaunchLatencySignal) *InstanceTypeProvider {
+	p.launchLatencySignal = signal
+	return p
+}
+
+// WithSpotAvailabilitySignal restricts spot capacity to the zones the signal reports that instance types are
+// offered as spot in when they're discovered. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithSpotAvailabilitySignal(signal SpotAvailabilitySignal) *InstanceTypeProvider {
+	p.spotAvailability = signal
+	return p
+}
+
+// WithCacheTTLs overrides how long instance type info and the instance types offered in each zone are
+// cached. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithCacheTTLs(instanceTypeInfoTTL time.Duration, offeringsTTL time.Duration) *InstanceTypeProvider {
+	p.instanceTypeInfoTTL = instanceTypeInfoTTL
+	p.offeringsTTL = offeringsTTL
+	return p
+}
+
+// WithCache overrides how long entries are cached by default, including the instance types offered in each
+// zone, and how often expired entries are evicted, e.g. to expire them almost immediately in tests or to
+// reduce API calls in large clusters. Cached entries are kept. It returns the same provider simply for ease
+// of use.
+func (p *InstanceTypeProvider) WithCache(ttl time.Duration, cleanupInterval time.Duration) *InstanceTypeProvider {
+	p.cache = cache.NewFrom(ttl, cleanupInterval, p.cache.Items())
+	p.offeringsTTL = ttl
+	return p
+}
+
+// WithPrefixDelegation caps the pods packed onto selected instance types by the number of IPv4 prefixes,
+// rather than addresses, their network interfaces support, for clusters whose VPC CNI has prefix delegation
+// enabled. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithPrefixDelegation(enabled bool) *InstanceTypeProvider {
+	p.prefixDelegation = enabled
+	return p
+}
+
+// WithRegionalOfferingsFallback selects instance types that aren't offered in any availability zone, but are offered
+// in the region, as if they were offered in every zone that offers other instance types. In some opt-in regions,
+// zonal offerings only cover the zones that are enabled, so instance types would otherwise be excluded even though
+// they're offered. Launches in zones that don't offer them fail. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithRegionalOfferingsFallback(enabled bool) *InstanceTypeProvider {
+	p.regionalOfferings = enabled
+	return p
+}
+
+// regionOf returns the region the EC2 client is configured for, or empty if it isn't an EC2 client
+func regionOf(ec2api ec2iface.EC2API) string {
+	if client, ok := ec2api.(*ec2.EC2); ok {
+		return aws.StringValue(client.Config.Region)
+	}
+	return ""
+}
+
+// NewStaticInstanceTypeProvider returns a provider that serves the given instance types instead of
+// discovering them from EC2, e.g. to exercise selection with instance types that don't exist in AWS.
+func NewStaticInstanceTypeProvider(instanceTypes []*packing.Instance) *InstanceTypeProvider {
+	p := &InstanceTypeProvider{
+		cache:               cache.New(CacheTTL, CacheCleanupInterval),
+		version:             1,
+		capacitySignal:      noCapacitySignal{},
+		launchLatencySignal: noCapacitySignal{},
+		spotAvailability:    noCapacitySignal{},
+	}
+	currentGeneration := []*packing.Instance{}
+	instanceTypeInfo := []*ec2.InstanceTypeInfo{}
+	for _, instanceType := range instanceTypes {
+		if aws.BoolValue(instanceType.CurrentGeneration) {
+			currentGeneration = append(currentGeneration, instanceType)
+		}
+		instanceTypeInfo = append(instanceTypeInfo, &instanceType.InstanceTypeInfo)
 	}
+	p.cache.Set(instanceTypeInfoKeyPrefix+allInstanceTypesKey, instanceTypeInfo, cache.NoExpiration)
+	p.cache.Set(allInstanceTypesKey, instanceTypes, cache.NoExpiration)
+	p.cache.Set(currentGenerationInstanceTypesKey, currentGeneration, cache.NoExpiration)
+	return p.withDefaultFilters()
 }
 
-// Get instance types that are availble per availability zone
+// Get instance types that are availble per availability zone. It returns an error if every pod is
+// larger than every instance type that the instance type constraints allow, or a
+// NoMatchingInstanceTypesError if no instance types satisfy the constraints. Pods that are larger
+// than every instance type are left for the packer to skip, so they don't prevent packing others.
 func (p *InstanceTypeProvider) Get(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints) ([]*packing.Instance, error) {
-	zones := []string{}
-	for zone := range zonalSubnetOptions {
-		zones = append(zones, zone)
+	result, err := p.GetResult(ctx, zonalSubnetOptions, constraints)
+	if err != nil {
+		return nil, err
 	}
+	if tooSmall := result.Eliminated["minResources"]; len(result.Instances) == 0 && tooSmall > 0 &&
+		tooSmall == result.Considered-result.Eliminated["malformed"]-result.Eliminated["instanceType"]-result.Eliminated["excluded"] {
+		if len(constraints.Pods) > 1 {
+			return nil, fmt.Errorf("requests of all %d pods exceed all %d allowed instance types", len(constraints.Pods), tooSmall)
+		}
+		requests := resources.RequestsForPods(constraints.Pods...)
+		return nil, fmt.Errorf("pod requests of %s cpu and %s memory exceed all %d allowed instance types",
+			requests.Cpu(), requests.Memory(), tooSmall)
+	}
+	if len(result.Instances) == 0 {
+		return nil, noMatchingInstanceTypesErrorFor(result)
+	}
+	return result.Instances, nil
+}
 
-	var supportedInstanceTypes []*packing.Instance
-	if instanceTypes, ok := p.cache.Get(allInstanceTypesKey); ok {
-		supportedInstanceTypes = instanceTypes.([]*packing.Instance)
-	} else {
-		var err error
-		supportedInstanceTypes, err = p.getZonalInstanceTypes(ctx)
+// GetByZone returns the instance types that Get would, grouped by each of the zones they're offered in, in the
+// order Get returns them. Only the zones of the subnets are included if there are any, e.g. for the packer to
+// look up the instance types available in a zone without inverting each instance type's zones.
+func (p *InstanceTypeProvider) GetByZone(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints) (map[string][]*packing.Instance, error) {
+	instanceTypes, err := p.Get(ctx, zonalSubnetOptions, constraints)
+	if err != nil {
+		return nil, err
+	}
+	byZone := map[string][]*packing.Instance{}
+	for _, instanceType := range instanceTypes {
+		for _, zone := range instanceType.Zones {
+			if _, ok := zonalSubnetOptions[zone]; ok || len(zonalSubnetOptions) == 0 {
+				byZone[zone] = append(byZone[zone], instanceType)
+			}
+		}
+	}
+	return byZone, nil
+}
+
+// NoMatchingInstanceTypesError is returned by Get if no instance types satisfy the constraints
+type NoMatchingInstanceTypesError struct {
+	// Considered is the number of candidate instance types, which is zero if none are offered in the zones
+	Considered int
+	// Eliminated counts the candidates rejected by each predicate, keyed by predicate name
+	Eliminated map[string]int
+	// Binding are the names of the predicates that eliminated candidates, from most to fewest eliminated,
+	// i.e. the constraints that are most worth relaxing
+	Binding []string
+}
+
+func (e *NoMatchingInstanceTypesError) Error() string {
+	if e.Considered == 0 {
+		return "no instance types satisfy the constraints, none were considered"
+	}
+	eliminated := []string{}
+	for _, name := range e.Binding {
+		eliminated = append(eliminated, fmt.Sprintf("%d by %s", e.Eliminated[name], name))
+	}
+	return fmt.Sprintf("no instance types satisfy the constraints, all %d were eliminated, %s", e.Considered, strings.Join(eliminated, ", "))
+}
+
+// noMatchingInstanceTypesErrorFor orders the predicates of the selection result by how many candidates they eliminated
+func noMatchingInstanceTypesErrorFor(result *SelectionResult) *NoMatchingInstanceTypesError {
+	binding := []string{}
+	for name := range result.Eliminated {
+		binding = append(binding, name)
+	}
+	sort.Slice(binding, func(i, j int) bool {
+		if result.Eliminated[binding[i]] != result.Eliminated[binding[j]] {
+			return result.Eliminated[binding[i]] > result.Eliminated[binding[j]]
+		}
+		return binding[i] < binding[j]
+	})
+	return &NoMatchingInstanceTypesError{Considered: result.Considered, Eliminated: result.Eliminated, Binding: binding}
+}
+
+// GetResult returns the instance types that are available per availability
+// zone along with diagnostics describing how the constraints were applied
+func (p *InstanceTypeProvider) GetResult(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints) (*SelectionResult, error) {
+	return p.getResult(ctx, zonalSubnetOptions, constraints, nil)
+}
+
+// getResult returns the selection result for the constraints that also satisfies the requirements, if any
+func (p *InstanceTypeProvider) getResult(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints, requirements *InstanceRequirements) (*SelectionResult, error) {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, constraints.CurrentGenerationOnly, constraints.InstanceTypeFilters)
+	if err != nil {
+		return nil, err
+	}
+	exhausted := exhaustedZonesOf(zonalSubnetOptions, constraints.MinSubnetAvailableIPs)
+	constraints.ExcludedZones = append(append([]string{}, constraints.ExcludedZones...), exhausted...)
+	result := p.selectCached(supportedInstanceTypes, constraints, requirements, zonesFrom(zonalSubnetOptions))
+	for _, zone := range exhausted {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("excluded zone %s, its subnets have too few available IP addresses", zone))
+	}
+	p.withPrices(ctx, result.Instances)
+	p.withInterruptionScores(ctx, result.Instances)
+	if p.scorer != nil && len(result.Instances) > 0 {
+		result.Instances = p.scored(result.Instances)
+		if len(constraints.Pods) > 0 {
+			requests := resources.Merge(resources.RequestsForPods(constraints.Pods...), constraints.Overhead)
+			result.Efficiency, _ = fitOf(result.Instances[0], requests, constraints.SizeDimension)
+		}
+	}
+	return result, nil
+}
+
+// selectCached selects the instance types for the constraints, and requirements if any, in the zones, falling back
+// to on-demand if allowed.
+// Results are cached for SelectionCacheTTL, keyed by the discovery version, so that identical selections, e.g.
+// while provisioning a burst of pods, aren't filtered again until the instance types are rediscovered. Callers
+// receive a copy, since prices and interruption scores are set on the selected instance types.
+func (p *InstanceTypeProvider) selectCached(instanceTypes []*packing.Instance, constraints Constraints, requirements *InstanceRequirements, zones []string) *SelectionResult {
+	key, err := selectionKeyFor(p.Version(), p.filterNames(), constraints, requirements, zones)
+	if err != nil {
+		zap.S().Debugf("Not caching the selection, %s", err.Error())
+	} else if cached, ok := p.cache.Get(key); ok {
+		recordCacheLookup(selectionsCache, true)
+		return cached.(*SelectionResult).copy()
+	}
+	result := p.selectFrom(instanceTypes, constraints, zones, requirements.predicates()...)
+	if len(result.Instances) == 0 && constraints.AllowOnDemandFallback && !functional.ContainsString(constraints.GetCapacityTypes(), capacityTypeOnDemand) {
+		result = p.selectOnDemandFallbackFrom(instanceTypes, constraints, requirements, zones, result)
+	}
+	if err == nil {
+		recordCacheLookup(selectionsCache, false)
+		p.cache.Set(key, result, SelectionCacheTTL)
+	}
+	return result.copy()
+}
+
+// selectionKeyFor returns the cache key of the selection for the constraints, requirements and zones as of the
+// discovery version, with the named filters registered. Pods are only keyed by their requests, which are all that
+// selection depends on, so that pods with identical requests share the selection.
+func selectionKeyFor(version uint64, filterNames []string, constraints Constraints, requirements *InstanceRequirements, zones []string) (string, error) {
+	podRequests, err := podRequestsKeyFor(constraints.Pods)
+	if err != nil {
+		return "", err
+	}
+	constraints.Pods = nil
+	encoded, err := json.Marshal([]interface{}{constraints, podRequests, requirements})
+	if err != nil {
+		return "", fmt.Errorf("encoding constraints, %w", err)
+	}
+	sorted := append([]string{}, zones...)
+	sort.Strings(sorted)
+	hash := fnv.New64a()
+	hash.Write(encoded)
+	fmt.Fprintf(hash, "%q%q", sorted, filterNames)
+	return fmt.Sprintf("%s%d/%s", selectionKeyPrefix, version, strconv.FormatUint(hash.Sum64(), 16)), nil
+}
+
+// podRequestsKeyFor returns the encoded requests of each of the pods, sorted since selection doesn't depend on
+// the pods' order
+func podRequestsKeyFor(pods []*v1.Pod) ([]string, error) {
+	podRequests := []string{}
+	for _, pod := range pods {
+		encoded, err := json.Marshal(resources.RequestsForPods(pod))
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("encoding pod requests, %w", err)
 		}
-		p.cache.SetDefault(allInstanceTypesKey, supportedInstanceTypes)
-		zap.S().Debugf("Successfully discovered %d EC2 instance types", len(supportedInstanceTypes))
+		podRequests = append(podRequests, string(encoded))
 	}
-	return p.filterFrom(supportedInstanceTypes, constraints, zones), nil
+	sort.Strings(podRequests)
+	return podRequests, nil
 }
 
-// GetAllInstanceTypeNames returns all instance type names without filtering based on constraints
-func (p *InstanceTypeProvider) GetAllInstanceTypeNames(ctx context.Context) ([]string, error) {
-	supportedInstanceTypes, err := p.Get(ctx, map[string][]*ec2.Subnet{}, Constraints{})
+// selectOnDemandFallbackFrom selects the instance types for on-demand instead of the constraints' capacity types,
+// marking each of them as a fallback, or returns the original result if no instance types can be selected either way
+func (p *InstanceTypeProvider) selectOnDemandFallbackFrom(instanceTypes []*packing.Instance, constraints Constraints, requirements *InstanceRequirements, zones []string, result *SelectionResult) *SelectionResult {
+	fallback := constraints
+	fallback.CapacityTypes = []string{capacityTypeOnDemand}
+	fallbackResult := p.selectFrom(instanceTypes, fallback, zones, requirements.predicates()...)
+	if len(fallbackResult.Instances) == 0 {
+		return result
+	}
+	for _, instanceType := range fallbackResult.Instances {
+		instanceType.OnDemandFallback = true
+	}
+	fallbackResult.Warnings = append(fallbackResult.Warnings, fmt.Sprintf("fell back to on-demand, no instance types could be selected for %s",
+		strings.Join(constraints.GetCapacityTypes(), ", ")))
+	return fallbackResult
+}
+
+// Explain returns the names of every predicate each candidate instance type fails for the constraints, keyed by
+// instance type, e.g. {"m5.large": [], "m6g.large": ["architecture", "zones"]}, to debug why instance types
+// weren't selected. Unlike the selection result, which attributes each candidate to the first predicate it fails,
+// candidates are checked against every predicate. Instance types that would be selected have no failures.
+func (p *InstanceTypeProvider) Explain(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints) (map[string][]string, error) {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, constraints.CurrentGenerationOnly, constraints.InstanceTypeFilters)
 	if err != nil {
 		return nil, err
 	}
-	instanceTypeNames := []string{}
+	exhausted := exhaustedZonesOf(zonalSubnetOptions, constraints.MinSubnetAvailableIPs)
+	constraints.ExcludedZones = append(append([]string{}, constraints.ExcludedZones...), exhausted...)
+	supportedInstanceTypes = withoutZones(wellFormed(supportedInstanceTypes), constraints.ExcludedZones)
+	predicates := p.selectionPredicatesFor(constraints, supportedInstanceTypes, zonesFrom(zonalSubnetOptions))
+	explanation := map[string][]string{}
 	for _, instanceType := range supportedInstanceTypes {
-		instanceTypeNames = append(instanceTypeNames, *instanceType.InstanceType)
+		explanation[*instanceType.InstanceType] = allFailing(predicates, instanceType)
+	}
+	return explanation, nil
+}
+
+// GetResultWithFallback returns the selection result of the first constraint set, in order, that any
+// instance types satisfy, along with its index. If none are satisfied, it returns the last set's result
+// and -1, e.g. to degrade gracefully from preferred to progressively looser constraints.
+func (p *InstanceTypeProvider) GetResultWithFallback(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, tiers []Constraints) (*SelectionResult, int, error) {
+	if len(tiers) == 0 {
+		return nil, -1, fmt.Errorf("no constraint sets to select from")
 	}
+	var result *SelectionResult
+	for i, constraints := range tiers {
+		var err error
+		if result, err = p.GetResult(ctx, zonalSubnetOptions, constraints); err != nil {
+			return nil, -1, err
+		}
+		if len(result.Instances) > 0 {
+			return result, i, nil
+		}
+		zap.S().Debugf("Falling back from constraint set %d of %d, %s", i+1, len(tiers), result.Summary())
+	}
+	return result, -1, nil
+}
+
+// GetWithRequirements returns the instance types that are available per availability zone and
+// satisfy both the constraints and the EC2 attribute-based instance type requirements, selected
+// like GetResult, e.g. excluding zones whose subnets are exhausted
+func (p *InstanceTypeProvider) GetWithRequirements(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints, requirements *InstanceRequirements) ([]*packing.Instance, error) {
+	result, err := p.getResult(ctx, zonalSubnetOptions, constraints, requirements)
+	if err != nil {
+		return nil, err
+	}
+	return result.Instances, nil
+}
+
+// TopKFit returns up to k instance types that fit all of the constraints' pods on a single node,
+// ranked by how efficiently the pods and overhead would use each instance type's size dimension
+func (p *InstanceTypeProvider) TopKFit(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints, k int) ([]*packing.Instance, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	instanceTypes, err := p.Get(ctx, zonalSubnetOptions, constraints)
+	if err != nil {
+		return nil, err
+	}
+	candidates := rankByFit(instanceTypes, constraints)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	return candidates, nil
+}
+
+// DiversifiedPools returns the capacity pools, i.e. instance type and zone pairs, of every instance type
+// that fits all of the constraints' pods on a single node. Pools are ordered to alternate between families,
+// so that the first maxPools are as diverse as possible. Zero maxPools returns every pool.
+func (p *InstanceTypeProvider) DiversifiedPools(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints, maxPools int) ([]CapacityPool, error) {
+	instanceTypes, err := p.Get(ctx, zonalSubnetOptions, constraints)
+	if err != nil {
+		return nil, err
+	}
+	families := []string{}
+	byFamily := map[string][]*packing.Instance{}
+	for _, instanceType := range rankByFit(instanceTypes, constraints) {
+		family := familyOf(*instanceType.InstanceType)
+		if _, ok := byFamily[family]; !ok {
+			families = append(families, family)
+		}
+		byFamily[family] = append(byFamily[family], instanceType)
+	}
+	zones := zonesFrom(zonalSubnetOptions)
+	pools := []CapacityPool{}
+	for round := 0; len(byFamily) > 0; round++ {
+		for _, family := range families {
+			if round >= len(byFamily[family]) {
+				delete(byFamily, family)
+				continue
+			}
+			instanceType := byFamily[family][round]
+			for _, zone := range instanceType.Zones {
+				if len(zones) == 0 || functional.ContainsString(zones, zone) {
+					pools = append(pools, CapacityPool{InstanceType: *instanceType.InstanceType, Zone: zone})
+				}
+			}
+		}
+	}
+	if maxPools > 0 && len(pools) > maxPools {
+		pools = pools[:maxPools]
+	}
+	return pools, nil
+}
+
+// GetAllInstanceTypeNames returns all instance type names, sorted, without filtering based on constraints.
+// Zones aren't needed to list names, so only instance type info is retrieved, not the instance types
+// offered in each zone, e.g. for webhooks that validate every provisioner update.
+func (p *InstanceTypeProvider) GetAllInstanceTypeNames(ctx context.Context) ([]string, error) {
+	instanceTypeInfo, _, err := p.getInstanceTypeInfo(ctx, allInstanceTypesKey, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving all instance types, %w", err)
+	}
+	instanceTypes := []*packing.Instance{}
+	for _, info := range instanceTypeInfo {
+		instanceTypes = append(instanceTypes, &packing.Instance{InstanceTypeInfo: *info})
+	}
+	instanceTypeNames := []string{}
+	for _, architecture := range []string{v1alpha1.ArchitectureAmd64, v1alpha1.ArchitectureArm64} {
+		constraints := Constraints{}
+		constraints.Architecture = aws.String(architecture)
+		for _, instanceType := range p.selectFrom(instanceTypes, constraints, nil).Instances {
+			instanceTypeNames = append(instanceTypeNames, *instanceType.InstanceType)
+		}
+	}
+	sort.Strings(instanceTypeNames)
 	return instanceTypeNames, nil
 }
 
-func (p *InstanceTypeProvider) getZonalInstanceTypes(ctx context.Context) ([]*packing.Instance, error) {
-	instanceTypes, err := p.getAllInstanceTypes(ctx)
+// GetSupportedArchitectures returns the distinct architectures supported by any instance type in the region,
+// normalized and sorted, e.g. ["arm64", "x86_64"] for validation to offer only the architectures available.
+// Like GetAllInstanceTypeNames, only the cached instance type info is retrieved.
+func (p *InstanceTypeProvider) GetSupportedArchitectures(ctx context.Context) ([]string, error) {
+	instanceTypeInfo, _, err := p.getInstanceTypeInfo(ctx, allInstanceTypesKey, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving all instance types, %w", err)
+	}
+	architectures := []string{}
+	for _, info := range instanceTypeInfo {
+		if info.ProcessorInfo == nil {
+			continue
+		}
+		for _, architecture := range info.ProcessorInfo.SupportedArchitectures {
+			if architecture != nil {
+				architectures = append(architectures, *utils.NormalizeArchitecture(architecture))
+			}
+		}
+	}
+	architectures = functional.UniqueStrings(architectures)
+	sort.Strings(architectures)
+	return architectures, nil
+}
+
+// GetNewInstanceTypeNames returns the names of the discovered instance types that aren't in the
+// baseline, sorted by name, e.g. to alert when new families launch in the region. Unlike
+// GetAllInstanceTypeNames, instance types that don't meet the default criteria are included.
+func (p *InstanceTypeProvider) GetNewInstanceTypeNames(ctx context.Context, baseline []string) ([]string, error) {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, instanceType := range supportedInstanceTypes {
+		if !functional.ContainsString(baseline, *instanceType.InstanceType) {
+			names = append(names, *instanceType.InstanceType)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetByName returns the instance type with the name, including its zones and the capacity types it's offered
+// as, without applying any constraints, e.g. to reconcile nodes whose instance type is already known. It returns
+// an UnknownInstanceTypesError if no instance type with the name is offered in the region.
+func (p *InstanceTypeProvider) GetByName(ctx context.Context, name string) (*packing.Instance, error) {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, instanceType := range wellFormed(supportedInstanceTypes) {
+		if *instanceType.InstanceType == name {
+			found := withCapacityTypes([]*packing.Instance{instanceType}, []string{capacityTypeOnDemand, capacityTypeSpot}, nil)[0]
+			found.PrefixDelegation = p.prefixDelegation
+			found.GPUReplicas = p.gpuReplicas[familyOf(name)]
+			return found, nil
+		}
+	}
+	return nil, &UnknownInstanceTypesError{Names: []string{name}}
+}
+
+// UnknownInstanceTypesError is returned by ValidateInstanceTypes and GetByName if instance type names aren't recognized
+type UnknownInstanceTypesError struct {
+	// Names are the unrecognized instance type names, in the order they were given
+	Names []string
+}
+
+func (e *UnknownInstanceTypesError) Error() string {
+	return fmt.Sprintf("unknown instance types %s", strings.Join(e.Names, ", "))
+}
+
+// ValidateInstanceTypes returns an UnknownInstanceTypesError naming each of the instance type names that
+// doesn't match an instance type in the region, e.g. because of a typo in Constraints.InstanceTypes, which
+// would otherwise never match and leave pods pending without explanation. Names are checked against all
+// instance types, including those that don't meet the default criteria, so it can validate provisioner
+// updates from a webhook without retrieving offerings.
+func (p *InstanceTypeProvider) ValidateInstanceTypes(ctx context.Context, names []string) error {
+	instanceTypeInfo, _, err := p.getInstanceTypeInfo(ctx, allInstanceTypesKey, false, nil)
+	if err != nil {
+		return fmt.Errorf("retrieving all instance types, %w", err)
+	}
+	known := map[string]bool{}
+	for _, info := range instanceTypeInfo {
+		known[aws.StringValue(info.InstanceType)] = true
+	}
+	unknown := []string{}
+	for _, name := range names {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	zap.S().Warnf("Ignoring unknown instance types %s", strings.Join(unknown, ", "))
+	return &UnknownInstanceTypesError{Names: unknown}
+}
+
+// GetGravitonMigrationCandidates maps each of the given x86_64 instance type names to the newest
+// generation arm64 instance type of the same category, attributes and size, if one is available
+func (p *InstanceTypeProvider) GetGravitonMigrationCandidates(ctx context.Context, instanceTypeNames []string) (map[string]string, error) {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	candidates := map[string]string{}
+	generations := map[string]int{}
+	for _, name := range instanceTypeNames {
+		x86 := parseFamily(familyOf(name))
+		for _, instanceType := range supportedInstanceTypes {
+			if !functional.ContainsString(supportedArchitecturesOf(instanceType), "arm64") ||
+				sizeOf(*instanceType.InstanceType) != sizeOf(name) {
+				continue
+			}
+			arm64 := parseFamily(familyOf(*instanceType.InstanceType))
+			if arm64.category == x86.category && arm64.attributes == x86.attributes && arm64.generation > generations[name] {
+				candidates[name] = *instanceType.InstanceType
+				generations[name] = arm64.generation
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// Warm discovers instance types so that subsequent calls are served from the cache, e.g. during
+// initialization so that the first provisioning decision doesn't wait for EC2's paginated APIs.
+func (p *InstanceTypeProvider) Warm(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("warming instance types, %w", err)
+	}
+	if _, err := p.getSupportedInstanceTypes(ctx, false, nil); err != nil {
+		return fmt.Errorf("warming instance types, %w", err)
+	}
+	return nil
+}
+
+// Healthy returns an error if instance types can't be discovered. Once discovered, the cached
+// instance types are used so that frequent health checks don't add pressure to the EC2 API.
+func (p *InstanceTypeProvider) Healthy(ctx context.Context) error {
+	supportedInstanceTypes, err := p.getSupportedInstanceTypes(ctx, false, nil)
+	if err != nil {
+		return fmt.Errorf("discovering instance types, %w", err)
+	}
+	if len(supportedInstanceTypes) == 0 {
+		return fmt.Errorf("no instance types were discovered")
+	}
+	return nil
+}
+
+// PermissionError is returned by Validate if the controller isn't permitted to call an EC2 API
+type PermissionError struct {
+	// Action is the IAM action that is missing, e.g. ec2:DescribeInstanceTypes
+	Action string
+	Err    error
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("missing permission %s, %s", e.Action, e.Err.Error())
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermissionError is true if the error is, or wraps, a PermissionError
+func IsPermissionError(err error) bool {
+	var permissionError *PermissionError
+	return errors.As(err, &permissionError)
+}
+
+// Validate makes a minimal call to each EC2 API used to discover instance types, so that missing permissions
+// are reported at startup, e.g. from a readiness probe, rather than when capacity is first provisioned. It
+// returns a PermissionError naming the missing permission if a call is unauthorized, or else any throttling
+// or transient error, which may succeed if retried. Unlike Healthy, it never uses cached instance types.
+func (p *InstanceTypeProvider) Validate(ctx context.Context) error {
+	if p.ec2api == nil {
+		return nil
+	}
+	if err := p.ec2api.DescribeInstanceTypesPagesWithContext(ctx, &ec2.DescribeInstanceTypesInput{MaxResults: aws.Int64(5)},
+		func(*ec2.DescribeInstanceTypesOutput, bool) bool { return false }); err != nil {
+		return validationErrorFor("ec2:DescribeInstanceTypes", err)
+	}
+	if err := p.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, &ec2.DescribeInstanceTypeOfferingsInput{MaxResults: aws.Int64(5)},
+		func(*ec2.DescribeInstanceTypeOfferingsOutput, bool) bool { return false }); err != nil {
+		return validationErrorFor("ec2:DescribeInstanceTypeOfferings", err)
+	}
+	return nil
+}
+
+// validationErrorFor returns a PermissionError for the action if the error is an authorization error
+func validationErrorFor(action string, err error) error {
+	if aerr, ok := err.(awserr.Error); ok && functional.ContainsString(authorizationErrorCodes, aerr.Code()) {
+		return &PermissionError{Action: action, Err: err}
+	}
+	if request.IsErrorThrottle(err) {
+		return fmt.Errorf("calling %s was throttled, %w", action, err)
+	}
+	return fmt.Errorf("calling %s, %w", action, err)
+}
+
+// Version returns a number that increases each time the discovered instance types are refreshed, so
+// that caches derived from them can be invalidated when it changes. It is zero before discovery.
+func (p *InstanceTypeProvider) Version() uint64 {
+	return atomic.LoadUint64(&p.version)
+}
+
+// getSupportedInstanceTypes returns the cached zonal instance types, discovering them if the cache is cold.
+// Current generation instance types, and those discovered with custom filters, are discovered and cached
+// separately from all instance types. They're composed from instance type info and zonal offerings, which
+// are cached independently, and expire with whichever expires first. Concurrent calls share a single
+// discovery while the cache is cold, rather than each describing the same instance types.
+func (p *InstanceTypeProvider) getSupportedInstanceTypes(ctx context.Context, currentGenerationOnly bool, filters map[string][]string) ([]*packing.Instance, error) {
+	key := allInstanceTypesKey
+	if currentGenerationOnly {
+		key = currentGenerationInstanceTypesKey
+	}
+	customFilters := ec2FiltersFrom(filters)
+	if len(customFilters) > 0 {
+		key = fmt.Sprintf("%s/%s", key, hashOf(customFilters))
+	}
+	instanceTypes, ok := p.cache.Get(key)
+	recordCacheLookup(instanceTypesCache, ok)
+	if ok {
+		return instanceTypes.([]*packing.Instance), nil
+	}
+	instanceTypes, err := p.discoveries.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return p.discoverInstanceTypes(ctx, key, currentGenerationOnly, customFilters)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instanceTypes.([]*packing.Instance), nil
+}
+
+// discoverInstanceTypes composes the zonal instance types from instance type info and zonal offerings, and
+// caches them under the key
+func (p *InstanceTypeProvider) discoverInstanceTypes(ctx context.Context, key string, currentGenerationOnly bool, customFilters []*ec2.Filter) ([]*packing.Instance, error) {
+	instanceTypeInfo, infoExpiration, err := p.getInstanceTypeInfo(ctx, key, currentGenerationOnly, customFilters)
 	if err != nil {
 		return nil, fmt.Errorf("retrieving all instance types, %w", err)
 	}
+	zonalInstanceTypeNames, offeringsExpiration, err := p.getZonalOfferings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	supportedInstanceTypes := zonalInstanceTypesFrom(instanceTypeInfo, zonalInstanceTypeNames)
+	if unoffered := unofferedFrom(instanceTypeInfo, supportedInstanceTypes); len(unoffered) > 0 {
+		supportedInstanceTypes = append(supportedInstanceTypes, p.regionallyOffered(ctx, unoffered, zonalInstanceTypeNames)...)
+	}
+	withZonalCapacityTypes(p.spotAvailability, supportedInstanceTypes)
+	expiration := infoExpiration
+	if offeringsExpiration.Before(expiration) {
+		expiration = offeringsExpiration
+	}
+	if ttl := time.Until(expiration); ttl > 0 {
+		p.cache.Set(key, supportedInstanceTypes, ttl)
+	}
+	atomic.AddUint64(&p.version, 1)
+	discoveredInstanceTypes.WithLabelValues(p.region, filtersLabelFor(key, customFilters)).Set(float64(len(supportedInstanceTypes)))
+	zap.S().Debugf("Successfully discovered %d EC2 instance types", len(supportedInstanceTypes))
+	return supportedInstanceTypes, nil
+}
 
+// getInstanceTypeInfo returns the cached instance type info and when it expires, retrieving it if the cache is cold
+func (p *InstanceTypeProvider) getInstanceTypeInfo(ctx context.Context, key string, currentGenerationOnly bool, customFilters []*ec2.Filter) ([]*ec2.InstanceTypeInfo, time.Time, error) {
+	key = instanceTypeInfoKeyPrefix + key
+	instanceTypes, expiration, ok := p.cache.GetWithExpiration(key)
+	recordCacheLookup(instanceTypeInfoCache, ok)
+	if ok {
+		return instanceTypes.([]*ec2.InstanceTypeInfo), expiration, nil
+	}
+	instanceTypeInfo, err := p.getAllInstanceTypes(ctx, currentGenerationOnly, customFilters)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	p.cache.Set(key, instanceTypeInfo, p.instanceTypeInfoTTL)
+	return instanceTypeInfo, time.Now().Add(p.instanceTypeInfoTTL), nil
+}
+
+// getZonalOfferings returns the cached instance type names offered in each zone and when they expire,
+// retrieving them if the cache is cold
+func (p *InstanceTypeProvider) getZonalOfferings(ctx context.Context) (map[string][]string, time.Time, error) {
+	offerings, expiration, ok := p.cache.GetWithExpiration(offeringsKey)
+	recordCacheLookup(offeringsCache, ok)
+	if ok {
+		return offerings.(map[string][]string), expiration, nil
+	}
 	inputs := &ec2.DescribeInstanceTypeOfferingsInput{
 		LocationType: aws.String("availability-zone"),
 	}
 
-	zonalInstanceTypeNames := map[string][]string{}
-	err = p.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, inputs, func(output *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
-		for _, offerings := range output.InstanceTypeOfferings {
-			zonalInstanceTypeNames[*offerings.Location] = append(zonalInstanceTypeNames[*offerings.Location], *offerings.InstanceType)
-		}
-		return true
+	var zonalInstanceTypeNames map[string][]string
+	err := p.describeBackoff.retry(ctx, "DescribeInstanceTypeOfferings", func() error {
+		zonalInstanceTypeNames = map[string][]string{}
+		return timed("DescribeInstanceTypeOfferings", func() error {
+			return p.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, inputs, func(output *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
+				for _, offerings := range output.InstanceTypeOfferings {
+					zonalInstanceTypeNames[*offerings.Location] = append(zonalInstanceTypeNames[*offerings.Location], *offerings.InstanceType)
+				}
+				return true
+			})
+		})
 	})
 	if err != nil {
-		return nil, fmt.Errorf("describing instance type zone offerings, %w", err)
+		return nil, time.Time{}, fmt.Errorf("describing instance type zone offerings, %w", err)
 	}
+	p.cache.Set(offeringsKey, zonalInstanceTypeNames, p.offeringsTTL)
+	return zonalInstanceTypeNames, time.Now().Add(p.offeringsTTL), nil
+}
 
-	// aggregate supported zones into each instance type
-	ec2InstanceTypes := map[string]*packing.Instance{}
+// zonalInstanceTypesFrom aggregates the sorted zones each instance type is offered in. Zones are indexed
+// by instance type in a single pass over the offerings, rather than searching the offerings of every zone
+// for each instance type, which is slow in regions with many zones and instance types.
+func zonalInstanceTypesFrom(instanceTypes []*ec2.InstanceTypeInfo, zonalInstanceTypeNames map[string][]string) []*packing.Instance {
+	zonesByInstanceType := map[string][]string{}
+	for zone, instanceTypeNames := range zonalInstanceTypeNames {
+		for _, instanceTypeName := range instanceTypeNames {
+			zonesByInstanceType[instanceTypeName] = append(zonesByInstanceType[instanceTypeName], zone)
+		}
+	}
 	supportedInstanceTypes := []*packing.Instance{}
 	for _, instanceTypeInfo := range instanceTypes {
-		for zone, instanceTypeNames := range zonalInstanceTypeNames {
-			for _, instanceTypeName := range instanceTypeNames {
-				if instanceTypeName == *instanceTypeInfo.InstanceType {
-					if it, ok := ec2InstanceTypes[instanceTypeName]; ok {
-						it.Zones = append(it.Zones, zone)
-					} else {
-						instanceType := &packing.Instance{InstanceTypeInfo: *instanceTypeInfo, Zones: []string{zone}}
-						supportedInstanceTypes = append(supportedInstanceTypes, instanceType)
-						ec2InstanceTypes[instanceTypeName] = instanceType
-					}
-				}
-			}
+		if instanceTypeInfo.InstanceType == nil {
+			zap.S().Debugf("Skipping instance type with incomplete info, missing InstanceType")
+			continue
+		}
+		zones, ok := zonesByInstanceType[*instanceTypeInfo.InstanceType]
+		if !ok {
+			continue
 		}
+		sort.Strings(zones)
+		supportedInstanceTypes = append(supportedInstanceTypes, &packing.Instance{InstanceTypeInfo: *instanceTypeInfo, Zones: zones})
 	}
-	return supportedInstanceTypes, nil
+	return supportedInstanceTypes
+}
+
+// unofferedFrom returns the instance type info of the instance types that aren't offered in any zone
+func unofferedFrom(instanceTypes []*ec2.InstanceTypeInfo, offered []*packing.Instance) []*ec2.InstanceTypeInfo {
+	offeredNames := map[string]bool{}
+	for _, instanceType := range offered {
+		offeredNames[*instanceType.InstanceType] = true
+	}
+	unoffered := []*ec2.InstanceTypeInfo{}
+	for _, instanceTypeInfo := range instanceTypes {
+		if instanceTypeInfo.InstanceType != nil && !offeredNames[*instanceTypeInfo.InstanceType] {
+			unoffered = append(unoffered, instanceTypeInfo)
+		}
+	}
+	return unoffered
+}
+
+// regionallyOffered returns the instance types that aren't offered in any zone but are offered in the region, as if
+// they were offered in every zone with offerings, if the fallback to regional offerings is enabled. Instance types
+// that are excluded are logged, since they'd otherwise silently disappear from selections.
+func (p *InstanceTypeProvider) regionallyOffered(ctx context.Context, unoffered []*ec2.InstanceTypeInfo, zonalInstanceTypeNames map[string][]string) []*packing.Instance {
+	regional := map[string]bool{}
+	if p.regionalOfferings {
+		var err error
+		if regional, err = p.getRegionalOfferings(ctx); err != nil {
+			zap.S().Warnf("Continuing without regional offerings, %s", err.Error())
+		}
+	}
+	zones := []string{}
+	for zone := range zonalInstanceTypeNames {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	instanceTypes := []*packing.Instance{}
+	excluded := []string{}
+	for _, instanceTypeInfo := range unoffered {
+		if !regional[*instanceTypeInfo.InstanceType] {
+			excluded = append(excluded, *instanceTypeInfo.InstanceType)
+			continue
+		}
+		instanceTypes = append(instanceTypes, &packing.Instance{InstanceTypeInfo: *instanceTypeInfo, Zones: append([]string{}, zones...)})
+	}
+	if len(instanceTypes) > 0 {
+		zap.S().Warnf("Presuming %d instance types that are only offered in the region are offered in zones %s",
+			len(instanceTypes), strings.Join(zones, ", "))
+	}
+	if len(excluded) > 0 {
+		sort.Strings(excluded)
+		zap.S().Warnf("Excluding instance types that aren't offered in any availability zone, %s", strings.Join(excluded, ", "))
+	}
+	return instanceTypes
+}
+
+// getRegionalOfferings returns the names of the instance types offered in the region
+func (p *InstanceTypeProvider) getRegionalOfferings(ctx context.Context) (map[string]bool, error) {
+	inputs := &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(ec2.LocationTypeRegion),
+	}
+	var instanceTypeNames map[string]bool
+	err := p.describeBackoff.retry(ctx, "DescribeInstanceTypeOfferings", func() error {
+		instanceTypeNames = map[string]bool{}
+		return timed("DescribeInstanceTypeOfferings", func() error {
+			return p.ec2api.DescribeInstanceTypeOfferingsPagesWithContext(ctx, inputs, func(output *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
+				for _, offering := range output.InstanceTypeOfferings {
+					instanceTypeNames[aws.StringValue(offering.InstanceType)] = true
+				}
+				return true
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing instance type region offerings, %w", err)
+	}
+	return instanceTypeNames, nil
 }
 
-// getAllInstanceTypes retrieves all instance types from the ec2 DescribeInstanceTypes API using some opinionated filters
-func (p *InstanceTypeProvider) getAllInstanceTypes(ctx context.Context) ([]*ec2.InstanceTypeInfo, error) {
+// getAllInstanceTypes retrieves all instance types from the ec2 DescribeInstanceTypes API using some opinionated
+// filters, which EC2 ANDs with any custom filters
+func (p *InstanceTypeProvider) getAllInstanceTypes(ctx context.Context, currentGenerationOnly bool, customFilters []*ec2.Filter) ([]*ec2.InstanceTypeInfo, error) {
+	if p.ec2api == nil {
+		return nil, fmt.Errorf("static instance types can't be discovered with custom filters")
+	}
 	instanceTypes := []*ec2.InstanceTypeInfo{}
 	describeInstanceTypesInput := &ec2.DescribeInstanceTypesInput{
 		Filters: []*ec2.Filter{
@@ -134,9 +929,21 @@ func (p *InstanceTypeProvider) getAllInstanceTypes(ctx context.Context) ([]*ec2.
 			},
 		},
 	}
-	err := p.ec2api.DescribeInstanceTypesPagesWithContext(ctx, describeInstanceTypesInput, func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
-		instanceTypes = append(instanceTypes, page.InstanceTypes...)
-		return true
+	if currentGenerationOnly {
+		describeInstanceTypesInput.Filters = append(describeInstanceTypesInput.Filters, &ec2.Filter{
+			Name:   aws.String("current-generation"),
+			Values: []*string{aws.String("true")},
+		})
+	}
+	describeInstanceTypesInput.Filters = append(describeInstanceTypesInput.Filters, customFilters...)
+	err := p.describeBackoff.retry(ctx, "DescribeInstanceTypes", func() error {
+		instanceTypes = []*ec2.InstanceTypeInfo{}
+		return timed("DescribeInstanceTypes", func() error {
+			return p.ec2api.DescribeInstanceTypesPagesWithContext(ctx, describeInstanceTypesInput, func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
+				instanceTypes = append(instanceTypes, page.InstanceTypes...)
+				return true
+			})
+		})
 	})
 	if err != nil {
 		return nil, fmt.Errorf("fetching instance types using ec2.DescribeInstanceTypes, %w", err)
@@ -144,25 +951,306 @@ func (p *InstanceTypeProvider) getAllInstanceTypes(ctx context.Context) ([]*ec2.
 	return instanceTypes, nil
 }
 
-// filterFrom returns a filtered list of instance types based on the provided resource constraints
-func (p *InstanceTypeProvider) filterFrom(instanceTypes []*packing.Instance, constraints Constraints, zones []string) []*packing.Instance {
-	filtered := []*packing.Instance{}
-	for _, instanceTypeInfo := range instanceTypes {
-		requests := resources.RequestsForPods(constraints.Pods...)
-		if p.isInstanceTypeSupported(constraints.InstanceTypes, instanceTypeInfo) &&
-			p.isCapacityTypeSupported(constraints.GetCapacityType(), instanceTypeInfo) &&
-			p.isArchitectureSupported(utils.NormalizeArchitecture(constraints.Architecture), instanceTypeInfo) &&
-			p.isZonesSupported(zones, instanceTypeInfo) &&
-			p.isNvidiaGPUSupported(requests, instanceTypeInfo) &&
-			p.isAWSNeuronSupported(requests, instanceTypeInfo) {
-			filtered = append(filtered, instanceTypeInfo)
+// ec2FiltersFrom returns EC2 filters for the filter names and values, sorted by name so that equivalent
+// filters are cached under the same key
+func ec2FiltersFrom(filters map[string][]string) []*ec2.Filter {
+	ec2Filters := []*ec2.Filter{}
+	for name, values := range filters {
+		ec2Filters = append(ec2Filters, &ec2.Filter{Name: aws.String(name), Values: aws.StringSlice(values)})
+	}
+	sort.Slice(ec2Filters, func(i, j int) bool {
+		return *ec2Filters[i].Name < *ec2Filters[j].Name
+	})
+	return ec2Filters
+}
+
+// hashOf returns a hash of the filters' names and values
+func hashOf(filters []*ec2.Filter) string {
+	hash := fnv.New64a()
+	for _, filter := range filters {
+		fmt.Fprintf(hash, "%s=%q;", aws.StringValue(filter.Name), aws.StringValueSlice(filter.Values))
+	}
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// selectFrom applies each predicate, followed by any additional predicates, to the instance
+// types in order, recording the predicate responsible for eliminating each rejected candidate
+func (p *InstanceTypeProvider) selectFrom(instanceTypes []*packing.Instance, constraints Constraints, zones []string, additional ...predicate) *SelectionResult {
+	result := &SelectionResult{
+		Instances:    []*packing.Instance{},
+		Considered:   len(instanceTypes),
+		Eliminated:   map[string]int{},
+		CapacityType: constraints.GetCapacityType(),
+		Zones:        zones,
+	}
+	if named := wellFormed(instanceTypes); len(named) < len(instanceTypes) {
+		result.Eliminated["malformed"] = len(instanceTypes) - len(named)
+		instanceTypes = named
+	}
+	instanceTypes = withoutZones(instanceTypes, constraints.ExcludedZones)
+	defaultFamilies := p.defaultFamilyPrefixesFor(constraints.Preset, instanceTypes)
+	predicates := append(p.selectionPredicatesFor(constraints, instanceTypes, zones), additional...)
+	for _, instanceType := range instanceTypes {
+		if predicate := firstFailing(predicates, instanceType); predicate != nil {
+			result.Eliminated[predicate.name]++
+			continue
+		}
+		result.Instances = append(result.Instances, instanceType)
+	}
+	logStages(result.Considered, predicates, result.Eliminated)
+	result.Instances = withCapacityTypes(orderBySize(result.Instances), constraints.GetCapacityTypes(), zones)
+	if constraints.RequireMetal {
+		result.Instances = orderByFit(result.Instances, constraints)
+	}
+	result.Instances = rankByLaunchFailureRate(p.capacitySignal, result.Instances, zones)
+	result.Instances = rankByLaunchLatency(p.launchLatencySignal, result.Instances, constraints.RotationInterval)
+	result.Instances = preferCovered(result.Instances, constraints.Commitments, constraints.GetCapacityType())
+	if constraints.PreferBurstable {
+		result.Instances = preferBurstable(result.Instances)
+	}
+	result.Instances = preferFamilies(result.Instances, constraints.PreferredInstanceFamilies)
+	withArchitectures(result.Instances, architecturesFor(constraints))
+	for _, instanceType := range result.Instances {
+		instanceType.PrefixDelegation = p.prefixDelegation
+		instanceType.GPUReplicas = p.gpuReplicas[familyOf(*instanceType.InstanceType)]
+	}
+	if len(result.Instances) > 0 && len(constraints.Pods) > 0 {
+		requests := resources.Merge(resources.RequestsForPods(constraints.Pods...), constraints.Overhead)
+		result.Efficiency, _ = fitOf(result.Instances[0], requests, constraints.SizeDimension)
+	}
+	result.Warnings = append(result.Warnings, taintWarningsFor(constraints.Taints, result.Instances)...)
+	if tenancies := []string{"", ec2.TenancyDefault, ec2.TenancyDedicated, ec2.TenancyHost}; !functional.ContainsString(tenancies, constraints.Tenancy) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("unknown tenancy %q excludes every instance type", constraints.Tenancy))
+	}
+	if bootModes := []string{"", ec2.BootModeValuesUefi, ec2.BootModeValuesLegacyBios}; !functional.ContainsString(bootModes, constraints.BootMode) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("unknown boot mode %q excludes every instance type", constraints.BootMode))
+	}
+	if _, ok := presets[constraints.Preset]; constraints.Preset != "" && !ok {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("ignored unknown preset %q", constraints.Preset))
+	}
+	if len(constraints.InstanceTypes) == 0 && len(defaultFamilies) == 0 && result.Considered > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("none of the default instance families are offered in region %q", p.region))
+	}
+	if len(result.Instances) == 0 && result.Considered > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("over-constrained, all %d instance types were eliminated", result.Considered))
+	}
+	return result
+}
+
+// selectionPredicatesFor returns the predicates for the constraints, followed by the predicate for their group, if any,
+// and the filters registered in addition to the built-in ones
+func (p *InstanceTypeProvider) selectionPredicatesFor(constraints Constraints, instanceTypes []*packing.Instance, zones []string) []predicate {
+	predicates := p.predicatesFor(constraints, zones, instanceTypes)
+	if constraints.Group != nil {
+		predicates = append(predicates, predicate{name: "group", matches: p.matcherFor(constraints.Group, instanceTypes, zones)})
+	}
+	return append(predicates, p.filterPredicatesFor(constraints, zones, instanceTypes, false)...)
+}
+
+// matcherFor returns a function that is true if an instance type satisfies the constraint group
+func (p *InstanceTypeProvider) matcherFor(group *cloudprovider.ConstraintGroup, instanceTypes []*packing.Instance, zones []string) func(*packing.Instance) bool {
+	var predicates []predicate
+	if group.Constraints != nil {
+		constraints := Constraints(*group.Constraints)
+		constraints.Pods = nil
+		predicates = p.groupPredicatesFor(constraints, instanceTypes, zones)
+		if constraints.Group != nil {
+			predicates = append(predicates, predicate{name: "group", matches: p.matcherFor(constraints.Group, instanceTypes, zones)})
 		}
 	}
-	return filtered
+	allOf := []func(*packing.Instance) bool{}
+	for i := range group.AllOf {
+		allOf = append(allOf, p.matcherFor(&group.AllOf[i], instanceTypes, zones))
+	}
+	anyOf := []func(*packing.Instance) bool{}
+	for i := range group.AnyOf {
+		anyOf = append(anyOf, p.matcherFor(&group.AnyOf[i], instanceTypes, zones))
+	}
+	return func(instance *packing.Instance) bool {
+		if firstFailing(predicates, instance) != nil {
+			return false
+		}
+		for _, matches := range allOf {
+			if !matches(instance) {
+				return false
+			}
+		}
+		for _, matches := range anyOf {
+			if matches(instance) {
+				return true
+			}
+		}
+		return len(anyOf) == 0
+	}
+}
+
+// groupPredicatesFor returns the predicates for the fields a group's constraints set. Groups only narrow the
+// constraints they're part of, so unlike those constraints they have no default instance types, architecture or
+// capacity type, which would exclude instance types and architectures the constraints explicitly allow. Instance
+// types are only restricted to those the group names, or else to its preset's families, and its architectures
+// are checked by their own predicate.
+func (p *InstanceTypeProvider) groupPredicatesFor(constraints Constraints, instanceTypes []*packing.Instance, zones []string) []predicate {
+	_, labeledCapacityType := constraints.Labels[capacityTypeLabel]
+	predicates := []predicate{}
+	for _, predicate := range p.predicatesFor(constraints, zones, instanceTypes) {
+		switch predicate.name {
+		case "instanceType":
+			continue
+		case "capacityType":
+			if len(constraints.CapacityTypes) == 0 && !labeledCapacityType {
+				continue
+			}
+		}
+		predicates = append(predicates, predicate)
+	}
+	if len(constraints.InstanceTypes) != 0 || constraints.Preset != "" {
+		families := p.defaultFamilyPrefixesFor(constraints.Preset, instanceTypes)
+		predicates = append([]predicate{{name: "instanceType", matches: func(instance *packing.Instance) bool {
+			if len(constraints.InstanceTypes) != 0 {
+				return functional.ContainsString(constraints.InstanceTypes, *instance.InstanceType)
+			}
+			return hasAnyFamilyPrefix(*instance.InstanceType, families...)
+		}}}, predicates...)
+	}
+	return predicates
+}
+
+// predicatesFor returns the ordered predicates of the built-in filters, followed by those of the extended resources,
+// that an instance type must satisfy for the constraints, with their preset's defaults
+func (p *InstanceTypeProvider) predicatesFor(constraints Constraints, zones []string, instanceTypes []*packing.Instance) []predicate {
+	constraints = presets[constraints.Preset].defaultsFor(constraints)
+	return append(p.filterPredicatesFor(constraints, zones, instanceTypes, true), p.extendedResourcePredicatesFor(constraints.Pods)...)
+}
+
+// defaultFilters returns the built-in filters, in the order instance types are checked against them
+func (p *InstanceTypeProvider) defaultFilters() []namedFilter {
+	return []namedFilter{
+		{name: "instanceType", filter: preparedFilterFunc(func(constraints Constraints, zones []string, instanceTypes []*packing.Instance) func(*packing.Instance) bool {
+			defaultFamilies := p.defaultFamilyPrefixesFor(constraints.Preset, instanceTypes)
+			return func(instance *packing.Instance) bool {
+				return p.isInstanceTypeSupported(constraints.InstanceTypes, defaultFamilies, constraints.RequireMetal || constraints.IncludeMetal,
+					constraints.RequireFPGA || constraints.MinFPGAs > 0, defaultArchitectureFor(constraints), instance)
+			}
+		})},
+		{name: "excluded", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isNotExcluded(constraints.ExcludedInstanceTypes, constraints.ExcludedFamilies, instance)
+		})},
+		{name: "minResources", filter: preparedFilterFunc(func(constraints Constraints, zones []string, instanceTypes []*packing.Instance) func(*packing.Instance) bool {
+			podRequests := cpuAndMemoryRequestsOf(constraints.Pods)
+			return func(instance *packing.Instance) bool {
+				return p.isMinResourcesSupported(podRequests, instance)
+			}
+		})},
+		{name: "metal", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isMetalSupported(constraints.RequireMetal, instance)
+		})},
+		{name: "burstable", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return !constraints.ExcludeBurstable || !isBurstable(instance)
+		})},
+		{name: "nitro", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isNitroSupported(constraints.RequireNitro, instance)
+		})},
+		{name: "tenancy", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isTenancySupported(constraints.Tenancy, instance)
+		})},
+		{name: "bootMode", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isBootModeSupported(constraints.BootMode, instance)
+		})},
+		{name: "fpga", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isFPGASupported(constraints.RequireFPGA, constraints.MinFPGAs, instance)
+		})},
+		{name: "preset", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return presets[constraints.Preset].matchesAccelerators(instance, constraints)
+		})},
+		{name: "memory", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isMemorySupported(constraints.MinMemoryMiB, constraints.MaxMemoryMiB, instance)
+		})},
+		{name: "memoryPerVCPU", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isMemoryPerVCPUSupported(constraints.MinMemoryMiBPerVCPU, constraints.MaxMemoryMiBPerVCPU, instance)
+		})},
+		{name: "capacityType", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isCapacityTypeSupported(constraints.GetCapacityTypes(), zones, instance)
+		})},
+		{name: "architecture", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isAnyArchitectureSupported(architecturesFor(constraints), instance)
+		})},
+		{name: "minGeneration", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isMinGenerationSupported(constraints.MinGenerations, instance)
+		})},
+		{name: "microarchitecture", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isMicroarchitectureSupported(constraints.Microarchitectures, instance)
+		})},
+		{name: "threadsPerCore", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isConfigurableThreadsPerCoreSupported(constraints.RequireConfigurableThreadsPerCore, instance)
+		})},
+		{name: "physicalCores", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isPhysicalCoresSupported(constraints.MinPhysicalCores, instance)
+		})},
+		{name: "zones", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isZonesSupported(zones, len(constraints.ExcludedZones) != 0, instance)
+		})},
+		{name: "minZones", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isMinZonesSupported(constraints.MinZones, zones, instance)
+		})},
+		{name: "launchFailureRate", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isLaunchFailureRateSupported(constraints.MaxLaunchFailureRate, zones, instance)
+		})},
+		{name: "commitments", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isCommitmentCoverageSupported(constraints.Commitments, constraints.GetCapacityType(), instance)
+		})},
+		{name: "trunkENI", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isTrunkENISupported(constraints.RequireTrunkENI, instance)
+		})},
+		{name: "elasticIPs", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isElasticIPsSupported(constraints.MinElasticIPs, instance)
+		})},
+		{name: "ipv6", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isIPv6Supported(constraints.RequireIPv6, instance)
+		})},
+		{name: "jumboFrames", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isJumboFramesSupported(constraints.RequireJumboFrames, instance)
+		})},
+		{name: "efa", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isEFASupported(constraints.RequireEFA, constraints.MinEFAInterfaces, instance)
+		})},
+		{name: "networkBandwidth", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isNetworkBandwidthSupported(constraints.MinNetworkBandwidthGbps, instance)
+		})},
+		{name: "inTransitEncryption", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isInTransitEncryptionSupported(constraints.RequireInTransitEncryption, instance)
+		})},
+		{name: "ebsEncryption", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isEBSEncryptionSupported(constraints.RequireEBSEncryption, instance)
+		})},
+		{name: "ebsMaximumIOPS", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isEBSMaximumIOPSSupported(constraints.MinEBSMaximumIOPS, instance)
+		})},
+		{name: "ebsOptimization", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isEBSOptimizationSupported(constraints.RequireEBSOptimization, constraints.MinEBSBaselineThroughputMBps, instance)
+		})},
+		{name: "localStorage", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isLocalStorageSupported(constraints.RequireLocalStorage, constraints.MinLocalStorageGB, instance)
+		})},
+		{name: "headroom", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isHeadroomSupported(constraints.Overhead, constraints.Pods, instance)
+		})},
+		{name: "gpuPodsPerNode", filter: preparedFilterFunc(func(constraints Constraints, zones []string, instanceTypes []*packing.Instance) func(*packing.Instance) bool {
+			gpusPerPod := gpusPerPodFor(constraints.Pods)
+			return func(instance *packing.Instance) bool {
+				return p.isGPUPodsPerNodeSupported(constraints.GPUPodsPerNode, gpusPerPod, instance)
+			}
+		})},
+		{name: "vCPUsPerGPU", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isVCPUsPerGPUSupported(constraints.MinVCPUsPerGPU, constraints.MaxVCPUsPerGPU, instance)
+		})},
+		{name: "gpuMemory", filter: InstanceTypeFilterFunc(func(instance *packing.Instance, constraints Constraints, zones []string) bool {
+			return p.isGPUMemorySupported(constraints.MinGPUMemoryMiB, instance)
+		})},
+	}
 }
 
-func (p *InstanceTypeProvider) isInstanceTypeSupported(instanceTypeConstraints []string, instance *packing.Instance) bool {
-	if len(instanceTypeConstraints) == 0 && p.isDefaultInstanceType(instance) {
+func (p *InstanceTypeProvider) isInstanceTypeSupported(instanceTypeConstraints []string, defaultFamilies []string, metal bool, fpga bool, architecture *string, instance *packing.Instance) bool {
+	if len(instanceTypeConstraints) == 0 && p.isDefaultInstanceType(defaultFamilies, metal, fpga, architecture, instance) {
 		return true
 	}
 	if len(instanceTypeConstraints) != 0 && functional.ContainsString(instanceTypeConstraints, *instance.InstanceType) {
@@ -171,41 +1259,348 @@ func (p *InstanceTypeProvider) isInstanceTypeSupported(instanceTypeConstraints [
 	return false
 }
 
+// defaultArchitectureFor returns amd64 if the constraints don't specify an architecture, so that arm64 instance
+// types are only selected by default for workloads that request them, since their images may be amd64 only.
+// Constraint groups choose their own architectures, and specified architectures are checked by their own
+// predicate, so there's no default architecture for either.
+func defaultArchitectureFor(constraints Constraints) *string {
+	if constraints.Architecture != nil || len(constraints.Architectures) != 0 || constraints.Group != nil {
+		return nil
+	}
+	return &v1alpha1.ArchitectureAmd64
+}
+
+func (p *InstanceTypeProvider) isNotExcluded(excludedInstanceTypes []string, excludedFamilies []string, instance *packing.Instance) bool {
+	return !functional.ContainsString(excludedInstanceTypes, *instance.InstanceType) &&
+		!functional.HasAnyPrefix(familyOf(*instance.InstanceType), excludedFamilies...)
+}
+
 // isDefaultInstanceType returns true if the instance type provided conforms to the default instance type criteria
-// This function is used to make sure we launch instance types that are suited for general workloads
-func (p *InstanceTypeProvider) isDefaultInstanceType(instanceTypeInfo *packing.Instance) bool {
-	return instanceTypeInfo.FpgaInfo == nil &&
-		!*instanceTypeInfo.BareMetal &&
-		functional.HasAnyPrefix(*instanceTypeInfo.InstanceType,
-			"m", "c", "r", "a", // Standard
-			"t3", "t4", // Burstable
-			"p", "inf", "g", // Accelerators
-		)
+// This function is used to make sure we launch instance types that are suited for general workloads. Bare metal
+// instance types, or those that don't report whether they're bare metal, are only included if metal is requested,
+// and must support the default architecture, if any. Instance types with FPGAs are only included if FPGAs are
+// requested, in which case they're included regardless of the default families.
+func (p *InstanceTypeProvider) isDefaultInstanceType(defaultFamilies []string, metal bool, fpga bool, architecture *string, instanceTypeInfo *packing.Instance) bool {
+	hasFPGAs := instanceTypeInfo.FpgaInfo != nil
+	return (fpga || !hasFPGAs) &&
+		(metal || !aws.BoolValue(instanceTypeInfo.BareMetal)) &&
+		(architecture == nil || p.isArchitectureSupported(utils.NormalizeArchitecture(architecture), instanceTypeInfo)) &&
+		((fpga && hasFPGAs) || hasAnyFamilyPrefix(*instanceTypeInfo.InstanceType, defaultFamilies...))
+}
+
+// isMinResourcesSupported prunes instance types with fewer vCPUs or less memory than every pod requests, which no
+// pod could be packed onto, before the packer considers them
+func (p *InstanceTypeProvider) isMinResourcesSupported(podRequests []v1.ResourceList, instance *packing.Instance) bool {
+	if len(podRequests) == 0 {
+		return true
+	}
+	for _, requests := range podRequests {
+		if requests.Cpu().MilliValue() <= packing.VCPUsOf(instance)*1000 && requests.Memory().Value() <= packing.MemoryMiBOf(instance)*mebibyte {
+			return true
+		}
+	}
+	return false
+}
+
+// isMemorySupported excludes instance types without memory when either bound is set
+func (p *InstanceTypeProvider) isMemorySupported(minimum int64, maximum int64, instance *packing.Instance) bool {
+	if minimum == 0 && maximum == 0 {
+		return true
+	}
+	memory := packing.MemoryMiBOf(instance)
+	return memory > 0 && memory >= minimum && (maximum == 0 || memory <= maximum)
+}
+
+// isMemoryPerVCPUSupported excludes instance types without vCPUs or memory when either bound is set
+func (p *InstanceTypeProvider) isMemoryPerVCPUSupported(minimum int64, maximum int64, instance *packing.Instance) bool {
+	if minimum == 0 && maximum == 0 {
+		return true
+	}
+	if instance.VCpuInfo == nil || instance.MemoryInfo == nil {
+		return false
+	}
+	vcpus := aws.Int64Value(instance.VCpuInfo.DefaultVCpus)
+	memory := aws.Int64Value(instance.MemoryInfo.SizeInMiB)
+	if vcpus == 0 || memory == 0 {
+		return false
+	}
+	return memory >= minimum*vcpus && (maximum == 0 || memory <= maximum*vcpus)
+}
+
+// isFPGASupported requires FPGAs if they're required or there's a minimum number of them
+func (p *InstanceTypeProvider) isFPGASupported(required bool, minimum int64, instance *packing.Instance) bool {
+	if !required && minimum == 0 {
+		return true
+	}
+	if instance.FpgaInfo == nil {
+		return false
+	}
+	fpgas := int64(0)
+	for _, fpga := range instance.FpgaInfo.Fpgas {
+		fpgas += aws.Int64Value(fpga.Count)
+	}
+	return fpgas >= minimum
+}
+
+// isMetalSupported requires a metal size, e.g. m5.metal or m7i.metal-24xl, which run without a hypervisor
+func (p *InstanceTypeProvider) isMetalSupported(required bool, instance *packing.Instance) bool {
+	return !required || (aws.BoolValue(instance.BareMetal) && strings.HasPrefix(sizeOf(*instance.InstanceType), "metal"))
+}
+
+// isTenancySupported requires dedicated host support for host tenancy, and excludes every instance type for unknown
+// tenancies rather than risk placing nodes on shared hardware. EC2 doesn't report which instance types support
+// dedicated instances, so dedicated tenancy doesn't exclude any.
+func (p *InstanceTypeProvider) isTenancySupported(tenancy string, instance *packing.Instance) bool {
+	switch tenancy {
+	case "", ec2.TenancyDefault, ec2.TenancyDedicated:
+		return true
+	case ec2.TenancyHost:
+		return aws.BoolValue(instance.DedicatedHostsSupported)
+	default:
+		return false
+	}
+}
+
+// isBootModeSupported requires support for the boot mode, if any, excluding instance types that don't report theirs
+func (p *InstanceTypeProvider) isBootModeSupported(bootMode string, instance *packing.Instance) bool {
+	return bootMode == "" || functional.ContainsString(aws.StringValueSlice(instance.SupportedBootModes), bootMode)
+}
+
+// isNitroSupported requires the nitro hypervisor, or bare metal, which reports no hypervisor but runs on Nitro cards
+func (p *InstanceTypeProvider) isNitroSupported(required bool, instance *packing.Instance) bool {
+	hypervisor := aws.StringValue(instance.Hypervisor)
+	return !required || hypervisor == ec2.InstanceTypeHypervisorNitro || (hypervisor == "" && aws.BoolValue(instance.BareMetal))
 }
 
 func (p *InstanceTypeProvider) isArchitectureSupported(architecture *string, instance *packing.Instance) bool {
 	return architecture == nil ||
-		functional.ContainsString(aws.StringValueSlice(instance.ProcessorInfo.SupportedArchitectures), *architecture)
+		functional.ContainsString(supportedArchitecturesOf(instance), *architecture)
 }
 
-func (p *InstanceTypeProvider) isCapacityTypeSupported(capacityType string, instance *packing.Instance) bool {
-	return capacityType == "" ||
-		functional.ContainsString(aws.StringValueSlice(instance.SupportedUsageClasses), capacityType)
+// isAnyArchitectureSupported is true if there are no architectures or the instance type supports any of them
+func (p *InstanceTypeProvider) isAnyArchitectureSupported(architectures []string, instance *packing.Instance) bool {
+	if len(architectures) == 0 {
+		return true
+	}
+	for i := range architectures {
+		if p.isArchitectureSupported(utils.NormalizeArchitecture(&architectures[i]), instance) {
+			return true
+		}
+	}
+	return false
 }
 
-func (p *InstanceTypeProvider) isNvidiaGPUSupported(requests v1.ResourceList, instanceTypeInfo *packing.Instance) bool {
-	if _, ok := requests[resources.NvidiaGPU]; ok {
-		return instanceTypeInfo.GpuInfo != nil && *instanceTypeInfo.GpuInfo.Gpus[0].Manufacturer == "NVIDIA"
+// isMinGenerationSupported checks instance types that support arm64 against the arm64
+// minimum and all others against the amd64 minimum
+func (p *InstanceTypeProvider) isMinGenerationSupported(minGenerations map[string]int, instance *packing.Instance) bool {
+	architecture := v1alpha1.ArchitectureAmd64
+	if functional.ContainsString(supportedArchitecturesOf(instance), v1alpha1.ArchitectureArm64) {
+		architecture = v1alpha1.ArchitectureArm64
 	}
-	return true
+	minGeneration, ok := minGenerations[architecture]
+	return !ok || parseFamily(familyOf(*instance.InstanceType)).generation >= minGeneration
+}
+
+func (p *InstanceTypeProvider) isMicroarchitectureSupported(microarchitectureConstraints []string, instance *packing.Instance) bool {
+	if len(microarchitectureConstraints) == 0 {
+		return true
+	}
+	microarchitecture, ok := microarchitectures[familyOf(*instance.InstanceType)]
+	return ok && functional.ContainsString(microarchitectureConstraints, microarchitecture)
+}
+
+func (p *InstanceTypeProvider) isConfigurableThreadsPerCoreSupported(required bool, instance *packing.Instance) bool {
+	if !required {
+		return true
+	}
+	if instance.VCpuInfo == nil {
+		return false
+	}
+	threadsPerCore := map[int64]bool{}
+	for _, threads := range instance.VCpuInfo.ValidThreadsPerCore {
+		threadsPerCore[aws.Int64Value(threads)] = true
+	}
+	return len(threadsPerCore) > 1
 }
-func (p *InstanceTypeProvider) isAWSNeuronSupported(requests v1.ResourceList, instanceTypeInfo *packing.Instance) bool {
-	if _, ok := requests[resources.AWSNeuron]; ok {
-		return instanceTypeInfo.InferenceAcceleratorInfo != nil && *instanceTypeInfo.InferenceAcceleratorInfo.Accelerators[0].Manufacturer == "AWS"
+
+// isPhysicalCoresSupported requires at least the minimum number of physical cores, excluding instance types
+// whose default number of cores is unknown
+func (p *InstanceTypeProvider) isPhysicalCoresSupported(minimum int64, instance *packing.Instance) bool {
+	if minimum == 0 {
+		return true
+	}
+	return instance.VCpuInfo != nil && aws.Int64Value(instance.VCpuInfo.DefaultCores) >= minimum
+}
+
+// isCapacityTypeSupported requires support for any of the capacity types in any of the eligible zones
+func (p *InstanceTypeProvider) isCapacityTypeSupported(capacityTypes []string, zones []string, instance *packing.Instance) bool {
+	return len(supportedCapacityTypes(capacityTypes, zones, instance)) > 0
+}
+
+// isHeadroomSupported requires each pod to fit alongside the overhead
+func (p *InstanceTypeProvider) isHeadroomSupported(overhead v1.ResourceList, pods []*v1.Pod, instance *packing.Instance) bool {
+	if len(overhead) == 0 {
+		return true
+	}
+	headroom := packing.Headroom(instance, overhead)
+	for _, available := range headroom {
+		if available.Sign() < 0 {
+			return false
+		}
+	}
+	for _, pod := range pods {
+		for resourceName, quantity := range resources.RequestsForPods(pod) {
+			if available, ok := headroom[resourceName]; ok && quantity.Cmp(available) > 0 {
+				return false
+			}
+		}
 	}
 	return true
 }
 
-func (p *InstanceTypeProvider) isZonesSupported(zones []string, instance *packing.Instance) bool {
-	return len(zones) == 0 || len(functional.IntersectStringSlice(instance.Zones, zones)) > 0
+// isGPUPodsPerNodeSupported requires room for the pods' GPU requests, or a
+// single GPU each if they don't request any, so that the pods share a node
+func (p *InstanceTypeProvider) isGPUPodsPerNodeSupported(podsPerNode int, gpusPerPod int64, instance *packing.Instance) bool {
+	if podsPerNode == 0 {
+		return true
+	}
+	if gpusPerPod == 0 {
+		gpusPerPod = 1
+	}
+	return packing.CountSchedulableNvidiaGPUs(p.withGPUReplicas(instance)) >= int64(podsPerNode)*gpusPerPod
+}
+
+// isVCPUsPerGPUSupported excludes instance types without GPUs when either bound is set
+func (p *InstanceTypeProvider) isVCPUsPerGPUSupported(minimum int64, maximum int64, instance *packing.Instance) bool {
+	if minimum == 0 && maximum == 0 {
+		return true
+	}
+	gpus := packing.CountNvidiaGPUs(instance)
+	if gpus == 0 {
+		return false
+	}
+	vcpus := packing.VCPUsOf(instance)
+	return vcpus >= minimum*gpus && (maximum == 0 || vcpus <= maximum*gpus)
+}
+
+func (p *InstanceTypeProvider) isGPUMemorySupported(minimum int64, instance *packing.Instance) bool {
+	return minimum == 0 || (instance.GpuInfo != nil && aws.Int64Value(instance.GpuInfo.TotalGpuMemoryInMiB) >= minimum)
+}
+
+// isLaunchFailureRateSupported requires at least one eligible zone where launches fail no more often than the maximum
+func (p *InstanceTypeProvider) isLaunchFailureRateSupported(maximum float64, zones []string, instance *packing.Instance) bool {
+	return maximum == 0 || launchFailureRateOf(p.capacitySignal, instance, zones) <= maximum
+}
+
+// isCommitmentCoverageSupported only restricts capacity types that the commitments apply to
+func (p *InstanceTypeProvider) isCommitmentCoverageSupported(commitments *cloudprovider.CommitmentCoverage, capacityType string, instance *packing.Instance) bool {
+	return commitments == nil || !commitments.Required || !appliesTo(commitments, capacityType) || covers(commitments, instance)
+}
+
+func (p *InstanceTypeProvider) isTrunkENISupported(required bool, instance *packing.Instance) bool {
+	return !required || functional.ContainsString(trunkENIFamilies, familyOf(*instance.InstanceType))
+}
+
+func (p *InstanceTypeProvider) isElasticIPsSupported(minimum int64, instance *packing.Instance) bool {
+	if minimum == 0 {
+		return true
+	}
+	return instance.NetworkInfo != nil &&
+		aws.Int64Value(instance.NetworkInfo.MaximumNetworkInterfaces)*aws.Int64Value(instance.NetworkInfo.Ipv4AddressesPerInterface) >= minimum
+}
+
+func (p *InstanceTypeProvider) isJumboFramesSupported(required bool, instance *packing.Instance) bool {
+	return !required || aws.BoolValue(instance.CurrentGeneration) ||
+		functional.ContainsString(jumboFramePreviousGenerationFamilies, familyOf(*instance.InstanceType))
+}
+
+// isEFASupported requires EFA support if it's required or there's a minimum number of EFA interfaces
+func (p *InstanceTypeProvider) isEFASupported(required bool, minimum int64, instance *packing.Instance) bool {
+	if !required && minimum == 0 {
+		return true
+	}
+	if instance.NetworkInfo == nil || !aws.BoolValue(instance.NetworkInfo.EfaSupported) {
+		return false
+	}
+	return minimum == 0 || (instance.NetworkInfo.EfaInfo != nil && aws.Int64Value(instance.NetworkInfo.EfaInfo.MaximumEfaInterfaces) >= minimum)
+}
+
+func (p *InstanceTypeProvider) isNetworkBandwidthSupported(minimumGbps float64, instance *packing.Instance) bool {
+	baselineGbps, _ := instance.NetworkBandwidthGbps()
+	return minimumGbps == 0 || baselineGbps >= minimumGbps
+}
+
+func (p *InstanceTypeProvider) isInTransitEncryptionSupported(required bool, instance *packing.Instance) bool {
+	return !required || functional.ContainsString(inTransitEncryptionFamilies, familyOf(*instance.InstanceType))
+}
+
+func (p *InstanceTypeProvider) isEBSEncryptionSupported(required bool, instance *packing.Instance) bool {
+	return !required ||
+		(instance.EbsInfo != nil && aws.StringValue(instance.EbsInfo.EncryptionSupport) == ec2.EbsEncryptionSupportSupported)
+}
+
+// isEBSMaximumIOPSSupported requires EbsOptimizedInfo when a minimum is set. Instance types
+// that are EBS optimized by default but don't report their limits can't be verified, so are excluded.
+func (p *InstanceTypeProvider) isEBSMaximumIOPSSupported(minimum int64, instance *packing.Instance) bool {
+	if minimum == 0 {
+		return true
+	}
+	if instance.EbsInfo == nil || instance.EbsInfo.EbsOptimizedInfo == nil {
+		return false
+	}
+	return aws.Int64Value(instance.EbsInfo.EbsOptimizedInfo.MaximumIops) >= minimum
+}
+
+// isEBSOptimizationSupported excludes instance types that don't support EBS optimization when it's required,
+// including those that don't report it. A minimum baseline throughput also requires EbsOptimizedInfo, which is
+// only reported by instance types that support EBS optimization.
+func (p *InstanceTypeProvider) isEBSOptimizationSupported(required bool, minimumMBps float64, instance *packing.Instance) bool {
+	if !required && minimumMBps == 0 {
+		return true
+	}
+	if instance.EbsInfo == nil {
+		return false
+	}
+	if support := aws.StringValue(instance.EbsInfo.EbsOptimizedSupport); support != ec2.EbsOptimizedSupportDefault && support != ec2.EbsOptimizedSupportSupported {
+		return false
+	}
+	if minimumMBps == 0 {
+		return true
+	}
+	return instance.EbsInfo.EbsOptimizedInfo != nil &&
+		aws.Float64Value(instance.EbsInfo.EbsOptimizedInfo.BaselineThroughputInMBps) >= minimumMBps
+}
+
+// isLocalStorageSupported requires instance store volumes, summing the size of every disk towards the minimum
+func (p *InstanceTypeProvider) isLocalStorageSupported(required bool, minimumGB int64, instance *packing.Instance) bool {
+	if !required && minimumGB == 0 {
+		return true
+	}
+	if !aws.BoolValue(instance.InstanceStorageSupported) || instance.InstanceStorageInfo == nil {
+		return false
+	}
+	totalGB := int64(0)
+	for _, disk := range instance.InstanceStorageInfo.Disks {
+		totalGB += aws.Int64Value(disk.SizeInGB) * aws.Int64Value(disk.Count)
+	}
+	return totalGB >= minimumGB
+}
+
+// isZonesSupported requires the instance type to be offered in an eligible zone, and in any zone at all if
+// zones were excluded, since excluded zones were already removed from its zones
+func (p *InstanceTypeProvider) isZonesSupported(zones []string, excluded bool, instance *packing.Instance) bool {
+	return (!excluded || len(instance.Zones) > 0) &&
+		(len(zones) == 0 || len(functional.IntersectStringSlice(instance.Zones, zones)) > 0)
+}
+
+func (p *InstanceTypeProvider) isIPv6Supported(required bool, instance *packing.Instance) bool {
+	return !required || (instance.NetworkInfo != nil && aws.BoolValue(instance.NetworkInfo.Ipv6Supported))
+}
+
+// isMinZonesSupported counts the instance type's zones that are eligible, or all of its zones if unconstrained
+func (p *InstanceTypeProvider) isMinZonesSupported(minimum int, zones []string, instance *packing.Instance) bool {
+	if len(zones) == 0 {
+		return len(instance.Zones) >= minimum
+	}
+	return len(functional.IntersectStringSlice(instance.Zones, zones)) >= minimum
 }
@@ -0,0 +1,195 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/karpenter/pkg/packing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// nestedZonalInstanceTypesFrom is the original aggregation, which scans every zone's offerings for each
+// instance type. It's kept to verify that zonalInstanceTypesFrom is equivalent, and to benchmark against.
+func nestedZonalInstanceTypesFrom(instanceTypes []*ec2.InstanceTypeInfo, zonalInstanceTypeNames map[string][]string) []*packing.Instance {
+	ec2InstanceTypes := map[string]*packing.Instance{}
+	supportedInstanceTypes := []*packing.Instance{}
+	for _, instanceTypeInfo := range instanceTypes {
+		for zone, instanceTypeNames := range zonalInstanceTypeNames {
+			for _, instanceTypeName := range instanceTypeNames {
+				if instanceTypeName == *instanceTypeInfo.InstanceType {
+					if it, ok := ec2InstanceTypes[instanceTypeName]; ok {
+						it.Zones = append(it.Zones, zone)
+					} else {
+						instanceType := &packing.Instance{InstanceTypeInfo: *instanceTypeInfo, Zones: []string{zone}}
+						supportedInstanceTypes = append(supportedInstanceTypes, instanceType)
+						ec2InstanceTypes[instanceTypeName] = instanceType
+					}
+				}
+			}
+		}
+	}
+	for _, instanceType := range supportedInstanceTypes {
+		sort.Strings(instanceType.Zones)
+	}
+	return supportedInstanceTypes
+}
+
+// offeringsFor returns instance type info and offerings resembling a large region, where each instance
+// type is offered in a varying subset of the zones and some aren't offered at all
+func offeringsFor(zones int, instanceTypes int) ([]*ec2.InstanceTypeInfo, map[string][]string) {
+	instanceTypeInfo := []*ec2.InstanceTypeInfo{}
+	zonalInstanceTypeNames := map[string][]string{}
+	for i := 0; i < instanceTypes; i++ {
+		name := fmt.Sprintf("family%d.size%d", i/10, i%10)
+		instanceTypeInfo = append(instanceTypeInfo, &ec2.InstanceTypeInfo{InstanceType: aws.String(name)})
+		for z := 0; z < zones; z++ {
+			if (i+z)%(z+2) != 0 {
+				zone := fmt.Sprintf("test-zone-1%c", 'a'+z)
+				zonalInstanceTypeNames[zone] = append(zonalInstanceTypeNames[zone], name)
+			}
+		}
+	}
+	return instanceTypeInfo, zonalInstanceTypeNames
+}
+
+var _ = Describe("Aggregating Zonal Instance Types", func() {
+	It("should attach the same zones as the nested aggregation", func() {
+		instanceTypes, zonalInstanceTypeNames := offeringsFor(6, 200)
+		zonalInstanceTypeNames["test-zone-1a"] = append(zonalInstanceTypeNames["test-zone-1a"], "unknown.large")
+		Expect(zonalInstanceTypesFrom(instanceTypes, zonalInstanceTypeNames)).
+			Should(Equal(nestedZonalInstanceTypesFrom(instanceTypes, zonalInstanceTypeNames)))
+	})
+	It("should exclude instance types that aren't offered in any zone", func() {
+		instanceTypes := []*ec2.InstanceTypeInfo{{InstanceType: aws.String("m5.large")}, {InstanceType: aws.String("m5.xlarge")}}
+		zonalInstanceTypes := zonalInstanceTypesFrom(instanceTypes, map[string][]string{
+			"test-zone-1b": {"m5.large"},
+			"test-zone-1a": {"m5.large"},
+		})
+		Expect(zonalInstanceTypes).Should(HaveLen(1))
+		Expect(zonalInstanceTypes[0].Zones).Should(Equal([]string{"test-zone-1a", "test-zone-1b"}))
+	})
+})
+
+var _ = Describe("Overriding Default Families", func() {
+	instanceTypes := []*packing.Instance{
+		{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String("m5.large")}},
+		{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String("c5.large")}},
+		{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String("p3.8xlarge")}},
+	}
+
+	It("should select the default families without overrides", func() {
+		provider := &InstanceTypeProvider{region: "us-west-2"}
+		Expect(provider.defaultFamilyPrefixesFor("", instanceTypes)).Should(Equal([]string{"m", "c", "p"}))
+	})
+	It("should select the families of the provider's region", func() {
+		provider := (&InstanceTypeProvider{region: "us-west-1"}).WithRegionalDefaultFamilies(map[string][]string{
+			"us-west-1": {"m", "c"},
+		})
+		Expect(provider.defaultFamilyPrefixesFor("", instanceTypes)).Should(Equal([]string{"m", "c"}))
+	})
+	It("should ignore the families of other regions", func() {
+		provider := (&InstanceTypeProvider{region: "us-west-2"}).WithRegionalDefaultFamilies(map[string][]string{
+			"us-west-1": {"m", "c"},
+		})
+		Expect(provider.defaultFamilyPrefixesFor("", instanceTypes)).Should(Equal([]string{"m", "c", "p"}))
+	})
+	It("should not share overrides between providers", func() {
+		(&InstanceTypeProvider{region: "us-west-1"}).WithRegionalDefaultFamilies(map[string][]string{"us-west-1": {"m"}})
+		provider := &InstanceTypeProvider{region: "us-west-1"}
+		Expect(provider.defaultFamilyPrefixesFor("", instanceTypes)).Should(Equal([]string{"m", "c", "p"}))
+	})
+})
+
+var _ = Describe("Matching Family Prefixes", func() {
+	It("should match families of the prefix's category", func() {
+		Expect(hasAnyFamilyPrefix("m5.large", "m")).Should(BeTrue())
+		Expect(hasAnyFamilyPrefix("m6gd.large", "m")).Should(BeTrue())
+		Expect(hasAnyFamilyPrefix("d3en.xlarge", "d")).Should(BeTrue())
+		Expect(hasAnyFamilyPrefix("i3en.large", "i")).Should(BeTrue())
+		Expect(hasAnyFamilyPrefix("t3a.large", "t3")).Should(BeTrue())
+		Expect(hasAnyFamilyPrefix("inf1.xlarge", "inf")).Should(BeTrue())
+	})
+	It("should not match families of other categories that share the prefix", func() {
+		Expect(hasAnyFamilyPrefix("mac1.metal", "m")).Should(BeFalse())
+		Expect(hasAnyFamilyPrefix("dl1.24xlarge", "d")).Should(BeFalse())
+		Expect(hasAnyFamilyPrefix("inf1.xlarge", "i")).Should(BeFalse())
+		Expect(hasAnyFamilyPrefix("hpc6a.48xlarge", "h")).Should(BeFalse())
+		Expect(hasAnyFamilyPrefix("t4g.large", "t3")).Should(BeFalse())
+	})
+	It("should select only the storage families for the storage preset", func() {
+		instanceTypes := []*packing.Instance{}
+		for _, name := range []string{"mac1.metal", "dl1.24xlarge", "inf1.xlarge", "im4gn.large", "d3en.xlarge"} {
+			instanceTypes = append(instanceTypes, &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String(name)}})
+		}
+		provider := &InstanceTypeProvider{}
+		Expect(provider.defaultFamilyPrefixesFor("storage", instanceTypes)).Should(Equal([]string{"im", "d"}))
+		Expect(provider.defaultFamilyPrefixesFor("general", instanceTypes)).Should(BeEmpty())
+		Expect(provider.defaultFamilyPrefixesFor("", instanceTypes)).Should(Equal([]string{"inf"}))
+	})
+})
+
+var _ = Describe("Defaulting Presets", func() {
+	It("should only default the fields the constraints leave unset", func() {
+		defaulted := presets["general"].defaultsFor(Constraints{})
+		Expect([]int64{defaulted.MinMemoryMiBPerVCPU, defaulted.MaxMemoryMiBPerVCPU}).Should(Equal([]int64{2048, 4096}))
+		defaulted = presets["general"].defaultsFor(Constraints{MaxMemoryMiBPerVCPU: 16384})
+		Expect([]int64{defaulted.MinMemoryMiBPerVCPU, defaulted.MaxMemoryMiBPerVCPU}).Should(Equal([]int64{0, 16384}))
+		Expect(presets["storage"].defaultsFor(Constraints{}).RequireLocalStorage).Should(BeTrue())
+	})
+	It("should only require the preset's accelerators unless accelerators are constrained", func() {
+		instance := &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String("p3.2xlarge")}}
+		Expect(presets["gpu-training"].matchesAccelerators(instance, Constraints{})).Should(BeFalse())
+		Expect(presets["gpu-training"].matchesAccelerators(instance, Constraints{MinGPUMemoryMiB: 16384})).Should(BeTrue())
+		Expect(presets["general"].matchesAccelerators(instance, Constraints{})).Should(BeTrue())
+	})
+})
+
+var _ = Describe("Inferring CPU Manufacturers", func() {
+	instanceType := func(name string, architectures ...string) *packing.Instance {
+		return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+			InstanceType:  aws.String(name),
+			ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice(architectures)},
+		}}
+	}
+
+	It("should infer Graviton from the arm64 architecture", func() {
+		Expect(cpuManufacturerOf(instanceType("a1.large", "arm64"))).Should(Equal("amazon-web-services"))
+		Expect(cpuManufacturerOf(instanceType("m6g.large", "arm64"))).Should(Equal("amazon-web-services"))
+	})
+	It("should infer the manufacturer of other architectures from the family name", func() {
+		Expect(cpuManufacturerOf(instanceType("m5a.large", "x86_64"))).Should(Equal("amd"))
+		Expect(cpuManufacturerOf(instanceType("m5.large", "i386", "x86_64"))).Should(Equal("intel"))
+	})
+})
+
+func BenchmarkZonalInstanceTypesFrom(b *testing.B) {
+	instanceTypes, zonalInstanceTypeNames := offeringsFor(6, 600)
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			zonalInstanceTypesFrom(instanceTypes, zonalInstanceTypeNames)
+		}
+	})
+	b.Run("nested", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			nestedZonalInstanceTypesFrom(instanceTypes, zonalInstanceTypeNames)
+		}
+	})
+}
@@ -16,28 +16,72 @@ package aws_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
 	"github.com/awslabs/karpenter/pkg/cloudprovider"
 	cloudprovideraws "github.com/awslabs/karpenter/pkg/cloudprovider/aws"
 	"github.com/awslabs/karpenter/pkg/cloudprovider/aws/fake"
+	"github.com/awslabs/karpenter/pkg/packing"
+	"github.com/awslabs/karpenter/pkg/test"
+	"github.com/awslabs/karpenter/pkg/utils/resources"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 var (
 	instanceTypeMocks = map[string]*ec2.InstanceTypeInfo{
 		"m5.large": {
 			InstanceType:                  aws.String("m5.large"),
+			CurrentGeneration:             aws.Bool(true),
 			SupportedUsageClasses:         []*string{aws.String("on-demand")},
 			BurstablePerformanceSupported: aws.Bool(false),
 			BareMetal:                     aws.Bool(false),
 			ProcessorInfo: &ec2.ProcessorInfo{
 				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
 			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus:        aws.Int64(2),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1, 2}),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(8192),
+			},
+			EbsInfo: &ec2.EbsInfo{
+				EncryptionSupport:   aws.String(ec2.EbsEncryptionSupportSupported),
+				EbsOptimizedSupport: aws.String(ec2.EbsOptimizedSupportDefault),
+				EbsOptimizedInfo: &ec2.EbsOptimizedInfo{
+					BaselineIops:             aws.Int64(3600),
+					BaselineThroughputInMBps: aws.Float64(81.25),
+					MaximumIops:              aws.Int64(18750),
+					MaximumThroughputInMBps:  aws.Float64(593.75),
+				},
+			},
+			Hypervisor:               aws.String("nitro"),
+			InstanceStorageSupported: aws.Bool(false),
+			NetworkInfo: &ec2.NetworkInfo{
+				NetworkPerformance:        aws.String("Up to 10 Gigabit"),
+				MaximumNetworkInterfaces:  aws.Int64(3),
+				Ipv4AddressesPerInterface: aws.Int64(10),
+			},
 		},
 		"m6g.large": {
 			InstanceType:                  aws.String("m6g.large"),
@@ -47,6 +91,148 @@ var (
 			ProcessorInfo: &ec2.ProcessorInfo{
 				SupportedArchitectures: aws.StringSlice([]string{"arm64"}),
 			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus:        aws.Int64(2),
+				ValidThreadsPerCore: aws.Int64Slice([]int64{1}),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(8192),
+			},
+		},
+		"t3.large": {
+			InstanceType:                  aws.String("t3.large"),
+			SupportedUsageClasses:         []*string{aws.String("on-demand"), aws.String("spot")},
+			BurstablePerformanceSupported: aws.Bool(true),
+			BareMetal:                     aws.Bool(false),
+			ProcessorInfo: &ec2.ProcessorInfo{
+				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus: aws.Int64(2),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(8192),
+			},
+			EbsInfo: &ec2.EbsInfo{
+				EncryptionSupport: aws.String(ec2.EbsEncryptionSupportUnsupported),
+			},
+		},
+		"m5.xlarge": {
+			InstanceType:                  aws.String("m5.xlarge"),
+			SupportedUsageClasses:         []*string{aws.String("on-demand"), aws.String("spot")},
+			BurstablePerformanceSupported: aws.Bool(false),
+			BareMetal:                     aws.Bool(false),
+			ProcessorInfo: &ec2.ProcessorInfo{
+				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus: aws.Int64(4),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(16384),
+			},
+			NetworkInfo: &ec2.NetworkInfo{
+				MaximumNetworkInterfaces:  aws.Int64(4),
+				Ipv4AddressesPerInterface: aws.Int64(15),
+			},
+		},
+		"r5.large": {
+			InstanceType:                  aws.String("r5.large"),
+			SupportedUsageClasses:         []*string{aws.String("on-demand"), aws.String("spot")},
+			BurstablePerformanceSupported: aws.Bool(false),
+			BareMetal:                     aws.Bool(false),
+			ProcessorInfo: &ec2.ProcessorInfo{
+				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus: aws.Int64(2),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(16384),
+			},
+			NetworkInfo: &ec2.NetworkInfo{
+				MaximumNetworkInterfaces:  aws.Int64(3),
+				Ipv4AddressesPerInterface: aws.Int64(10),
+			},
+		},
+		"c5.xlarge": {
+			InstanceType:                  aws.String("c5.xlarge"),
+			SupportedUsageClasses:         []*string{aws.String("on-demand"), aws.String("spot")},
+			BurstablePerformanceSupported: aws.Bool(false),
+			BareMetal:                     aws.Bool(false),
+			ProcessorInfo: &ec2.ProcessorInfo{
+				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus: aws.Int64(4),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(8192),
+			},
+			NetworkInfo: &ec2.NetworkInfo{
+				MaximumNetworkInterfaces:  aws.Int64(3),
+				Ipv4AddressesPerInterface: aws.Int64(10),
+			},
+		},
+		"m6i.large": {
+			InstanceType:                  aws.String("m6i.large"),
+			SupportedUsageClasses:         []*string{aws.String("on-demand"), aws.String("spot")},
+			BurstablePerformanceSupported: aws.Bool(false),
+			BareMetal:                     aws.Bool(false),
+			Hypervisor:                    aws.String("nitro"),
+			ProcessorInfo: &ec2.ProcessorInfo{
+				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus: aws.Int64(2),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(8192),
+			},
+		},
+		"g4dn.xlarge": {
+			InstanceType:                  aws.String("g4dn.xlarge"),
+			SupportedUsageClasses:         []*string{aws.String("on-demand"), aws.String("spot")},
+			BurstablePerformanceSupported: aws.Bool(false),
+			BareMetal:                     aws.Bool(false),
+			ProcessorInfo: &ec2.ProcessorInfo{
+				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus: aws.Int64(4),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(16384),
+			},
+			GpuInfo: &ec2.GpuInfo{
+				Gpus: []*ec2.GpuDeviceInfo{{
+					Name:         aws.String("T4"),
+					Manufacturer: aws.String("NVIDIA"),
+					Count:        aws.Int64(1),
+				}},
+			},
+		},
+		"g4ad.xlarge": {
+			InstanceType:                  aws.String("g4ad.xlarge"),
+			SupportedUsageClasses:         []*string{aws.String("on-demand"), aws.String("spot")},
+			BurstablePerformanceSupported: aws.Bool(false),
+			BareMetal:                     aws.Bool(false),
+			ProcessorInfo: &ec2.ProcessorInfo{
+				SupportedArchitectures: aws.StringSlice([]string{"x86_64"}),
+			},
+			VCpuInfo: &ec2.VCpuInfo{
+				DefaultVCpus: aws.Int64(4),
+			},
+			MemoryInfo: &ec2.MemoryInfo{
+				SizeInMiB: aws.Int64(16384),
+			},
+			GpuInfo: &ec2.GpuInfo{
+				Gpus: []*ec2.GpuDeviceInfo{{
+					Name:         aws.String("Radeon Pro V520"),
+					Manufacturer: aws.String("AMD"),
+					Count:        aws.Int64(1),
+				}},
+			},
 		},
 	}
 	defaultArch = "amd64"
@@ -99,7 +285,7 @@ var _ = Describe("InstanceTypes", func() {
 			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
 
 			It("should not return any instance types", func() {
-				Expect(err).ShouldNot(HaveOccurred())
+				Expect(err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
 				Expect(len(instanceTypes)).Should(Equal(0))
 			})
 		})
@@ -122,31 +308,3565 @@ var _ = Describe("InstanceTypes", func() {
 			})
 		})
 
-	})
+		Context("With trunk ENI required", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{RequireTrunkENI: true})
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
 
-})
+			It("should only return instance types that support trunking", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(len(instanceTypes)).Should(Equal(1))
+				Expect(*instanceTypes[0].InstanceType).Should(Equal("m5.large"))
+			})
+		})
 
-// Test Helpers
+		Context("With a minimum EBS maximum IOPS", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
 
-func getInstanceTypeProviderMocks(zones []string, instanceTypes []string) ec2iface.EC2API {
-	ec2api := &fake.EC2API{
-		EC2Behavior: fake.EC2Behavior{
-			DescribeInstanceTypesOutput:         &ec2.DescribeInstanceTypesOutput{},
-			DescribeInstanceTypeOfferingsOutput: &ec2.DescribeInstanceTypeOfferingsOutput{},
-		},
-	}
+			It("should exclude instance types below the minimum or without EBS optimized info", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinEBSMaximumIOPS: 10000}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(len(instanceTypes)).Should(Equal(1))
+				Expect(*instanceTypes[0].InstanceType).Should(Equal("m5.large"))
+			})
+			It("should not compare against baseline IOPS", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinEBSMaximumIOPS: 18750}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(len(instanceTypes)).Should(Equal(1))
+			})
+			It("should exclude all instance types when the minimum is too high", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinEBSMaximumIOPS: 18751}))
+				Expect(err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
+				Expect(instanceTypes).Should(BeEmpty())
+			})
+		})
 
-	for _, instanceType := range instanceTypes {
-		ec2api.DescribeInstanceTypesOutput.InstanceTypes = append(ec2api.DescribeInstanceTypesOutput.InstanceTypes, instanceTypeMocks[instanceType])
-	}
-	for _, zone := range zones {
-		for _, instanceType := range instanceTypes {
-			offering := &ec2.InstanceTypeOffering{
-				InstanceType: &instanceType,
-				Location:     &zone,
+		Context("With preferred instance families", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should order instance types in the preferred family first", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{PreferredInstanceFamilies: []string{"t3"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(len(instanceTypes)).Should(Equal(2))
+				Expect(*instanceTypes[0].InstanceType).Should(Equal("t3.large"))
+			})
+			It("should fall back to all instance types when the preferred family is unavailable", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{PreferredInstanceFamilies: []string{"c6g"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(len(instanceTypes)).Should(Equal(2))
+			})
+			It("should pack instance types in the preferred family first, regardless of size", func() {
+				ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge", "c5.xlarge"})
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{
+					PreferredInstanceFamilies: []string{"c5"},
+					Pods: []*v1.Pod{test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+					}})},
+				})
+				instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				packings := packing.NewPacker().Pack(context.Background(), constraints.Pods, instanceTypes, &cloudprovider.Constraints{})
+				Expect(packings).Should(HaveLen(1))
+				Expect(instanceTypeNames(packings[0].InstanceTypes)).Should(Equal([]string{"c5.xlarge", "m5.large", "m5.xlarge"}))
+			})
+		})
+
+		Context("With a minimum number of zones", func() {
+			ec2api := getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+				"m5.large":  {"test-zone-1a"},
+				"m6g.large": {"test-zone-1a", "test-zone-1b"},
+				"t3.large":  {"test-zone-1a", "test-zone-1b", "test-zone-1c"},
+			})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{"test-zone-1a": nil, "test-zone-1b": nil, "test-zone-1c": nil}
+
+			It("should exclude instance types offered in too few zones", func() {
+				for minZones, expected := range map[int][]string{
+					0: {"m5.large", "t3.large"},
+					1: {"m5.large", "t3.large"},
+					2: {"t3.large"},
+					3: {"t3.large"},
+					4: {},
+				} {
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+						cloudprovideraws.Constraints(cloudprovider.Constraints{MinZones: minZones}))
+					expectSelected(instanceTypes, err, expected...)
+				}
+			})
+			It("should only count eligible zones", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{MinZones: 2})
+				constraints.Architecture = &v1alpha1.ArchitectureArm64
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(),
+					map[string][]*ec2.Subnet{"test-zone-1a": nil, "test-zone-1b": nil}, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6g.large"))
+			})
+		})
+
+		Context("With excluded zones", func() {
+			ec2api := getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+				"m5.large":  {"test-zone-1a"},
+				"t3.large":  {"test-zone-1a", "test-zone-1b"},
+				"m5.xlarge": {"test-zone-1b"},
+			})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+
+			It("should exclude instance types only offered in excluded zones, even with subnets there", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(),
+					map[string][]*ec2.Subnet{"test-zone-1a": nil, "test-zone-1b": nil},
+					cloudprovideraws.Constraints(cloudprovider.Constraints{ExcludedZones: []string{"test-zone-1a"}}))
+				expectSelected(instanceTypes, err, "t3.large", "m5.xlarge")
+				for _, instanceType := range instanceTypes {
+					Expect(instanceType.Zones).Should(ConsistOf("test-zone-1b"))
+				}
+			})
+			It("should exclude zones when the zones are unconstrained", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{},
+					cloudprovideraws.Constraints(cloudprovider.Constraints{ExcludedZones: []string{"test-zone-1b"}}))
+				expectSelected(instanceTypes, err, "m5.large", "t3.large")
+			})
+			It("should select nothing if every subnet's zone is excluded", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{"test-zone-1a": nil},
+					cloudprovideraws.Constraints(cloudprovider.Constraints{ExcludedZones: []string{"test-zone-1a"}}))
+				expectSelected(instanceTypes, err)
+			})
+			It("should exclude zones whose subnets have no available IP addresses", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), map[string][]*ec2.Subnet{
+					"test-zone-1a": {{SubnetId: aws.String("subnet-1"), AvailableIpAddressCount: aws.Int64(0)}},
+					"test-zone-1b": {{SubnetId: aws.String("subnet-2"), AvailableIpAddressCount: aws.Int64(100)}},
+				}, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("t3.large", "m5.xlarge"))
+				Expect(result.Warnings).Should(ContainElement("excluded zone test-zone-1a, its subnets have too few available IP addresses"))
+			})
+			It("should keep zones where any subnet has available IP addresses, or doesn't report them", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{
+					"test-zone-1a": {
+						{SubnetId: aws.String("subnet-1"), AvailableIpAddressCount: aws.Int64(0)},
+						{SubnetId: aws.String("subnet-2"), AvailableIpAddressCount: aws.Int64(1)},
+					},
+					"test-zone-1b": {{SubnetId: aws.String("subnet-3")}},
+				}, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				expectSelected(instanceTypes, err, "m5.large", "t3.large", "m5.xlarge")
+			})
+			It("should exclude zones whose subnets have fewer available IP addresses than the minimum", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{
+					"test-zone-1a": {{SubnetId: aws.String("subnet-1"), AvailableIpAddressCount: aws.Int64(100)}},
+					"test-zone-1b": {{SubnetId: aws.String("subnet-2"), AvailableIpAddressCount: aws.Int64(5)}},
+				}, cloudprovideraws.Constraints(cloudprovider.Constraints{MinSubnetAvailableIPs: 10}))
+				expectSelected(instanceTypes, err, "m5.large", "t3.large")
+			})
+		})
+
+		Context("With default instance types for an architecture", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			DescribeTable("should only select instance types supporting the architecture, or amd64 if it's unset",
+				func(architecture *string, expected string) {
+					constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+					constraints.Architecture = architecture
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf(expected))
+				},
+				Entry("unset", nil, "m5.large"),
+				Entry("amd64", &v1alpha1.ArchitectureAmd64, "m5.large"),
+				Entry("arm64", &v1alpha1.ArchitectureArm64, "m6g.large"),
+			)
+			It("should not restrict explicit instance types to amd64", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.InstanceTypes = []string{"m5.large", "m6g.large"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m6g.large"))
+			})
+			It("should list the names of instance types of every architecture", func() {
+				instanceTypeNames, err := instanceTypeProvider.GetAllInstanceTypeNames(context.Background())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames).Should(Equal([]string{"m5.large", "m6g.large"}))
+			})
+		})
+
+		Context("With no instance types satisfying the constraints", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+
+			It("should identify the constraints that eliminated the most instance types", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Microarchitectures: []string{"graviton3"}})
+				constraints.Architecture = &v1alpha1.ArchitectureArm64
+				_, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{testZone: nil}, constraints)
+				noMatching := &cloudprovideraws.NoMatchingInstanceTypesError{}
+				Expect(errors.As(err, &noMatching)).Should(BeTrue())
+				Expect(noMatching.Considered).Should(Equal(3))
+				Expect(noMatching.Binding).Should(Equal([]string{"architecture", "microarchitecture"}))
+				Expect(err).Should(MatchError("no instance types satisfy the constraints, all 3 were eliminated, 2 by architecture, 1 by microarchitecture"))
+			})
+			It("should identify the zones if no instance types are offered in them", func() {
+				_, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{"test-zone-1b": nil},
+					cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				noMatching := &cloudprovideraws.NoMatchingInstanceTypesError{}
+				Expect(errors.As(err, &noMatching)).Should(BeTrue())
+				Expect(noMatching.Binding[0]).Should(Equal("zones"))
+			})
+		})
+
+		Context("With multiple architectures", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			architectures := func(instanceTypes []*packing.Instance) map[string]string {
+				architectures := map[string]string{}
+				for _, instanceType := range instanceTypes {
+					architectures[*instanceType.InstanceType] = instanceType.Architecture
+				}
+				return architectures
 			}
-			ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings = append(ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings, offering)
-		}
-	}
-	return ec2api
+
+			It("should select instance types of every architecture and tag them with their architecture", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.Architectures = []string{v1alpha1.ArchitectureArm64, v1alpha1.ArchitectureAmd64}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(architectures(instanceTypes)).Should(Equal(map[string]string{
+					"m5.large":  v1alpha1.ArchitectureAmd64,
+					"m6g.large": v1alpha1.ArchitectureArm64,
+				}))
+			})
+			It("should label instance types with their architecture", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.Architectures = []string{v1alpha1.ArchitectureAmd64, v1alpha1.ArchitectureArm64}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				for _, instanceType := range instanceTypes {
+					Expect(instanceType.Labels()).Should(HaveKeyWithValue(v1alpha1.ArchitectureLabelKey, instanceType.Architecture))
+				}
+			})
+			It("should replace the architecture", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.Architecture = &v1alpha1.ArchitectureAmd64
+				constraints.Architectures = []string{v1alpha1.ArchitectureArm64}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(architectures(instanceTypes)).Should(Equal(map[string]string{"m6g.large": v1alpha1.ArchitectureArm64}))
+			})
+			It("should tag instance types with a single architecture", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.Architecture = &v1alpha1.ArchitectureAmd64
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(architectures(instanceTypes)).Should(Equal(map[string]string{"m5.large": v1alpha1.ArchitectureAmd64}))
+			})
+		})
+
+		Context("With microarchitecture constraints", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should only return instance types with a matching microarchitecture", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Microarchitectures: []string{"graviton2", "graviton3"}})
+				constraints.Architecture = &v1alpha1.ArchitectureArm64
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6g.large"))
+			})
+			It("should exclude instance types with an unknown microarchitecture", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Microarchitectures: []string{"sapphire-rapids"}}))
+				Expect(err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
+				Expect(instanceTypes).Should(BeEmpty())
+			})
+		})
+
+		Context("With EBS encryption required", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should exclude instance types that don't support or don't report EBS encryption", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireEBSEncryption: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+			It("should include all instance types when not required", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.InstanceTypes = []string{"m5.large", "m6g.large", "t3.large"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m6g.large", "t3.large"))
+			})
+		})
+
+		Context("With configurable threads per core required", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{RequireConfigurableThreadsPerCore: true}))
+
+			It("should exclude instance types locked to a single threads per core value", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+		})
+
+		Context("With a minimum number of physical cores", func() {
+			withCores := func(name string, vCPUs int64, cores *int64) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				instanceTypeInfo.VCpuInfo = &ec2.VCpuInfo{DefaultVCpus: aws.Int64(vCPUs), DefaultCores: cores}
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				withCores("m5.xlarge", 4, aws.Int64(2)),
+				withCores("m6g.xlarge", 4, aws.Int64(4)),
+				withCores("m5.2xlarge", 8, aws.Int64(4)),
+				withCores("m4.xlarge", 4, nil),
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			names := []string{"m5.xlarge", "m6g.xlarge", "m5.2xlarge", "m4.xlarge"}
+
+			It("should count physical cores rather than vCPUs", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{MinPhysicalCores: 4})
+				constraints.InstanceTypes = names
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("m6g.xlarge", "m5.2xlarge"))
+				Expect(result.Eliminated).Should(Equal(map[string]int{"physicalCores": 2}))
+			})
+			It("should not exclude instance types whose cores are unknown if unconstrained", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.InstanceTypes = names
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf(names))
+			})
+		})
+
+		Context("With a minimum number of elastic IPs", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should exclude instance types with too few addresses across interfaces", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinElasticIPs: 30}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+				instanceTypes, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinElasticIPs: 31}))
+				Expect(err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
+				Expect(instanceTypes).Should(BeEmpty())
+			})
+		})
+
+		Context("With a workload preset", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge", "r5.large", "c5.xlarge", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should select the preset's families and memory to vCPU ratio", func() {
+				for preset, expected := range map[string][]string{
+					"general": {"m5.large", "m5.xlarge", "t3.large"},
+					"compute": {"c5.xlarge"},
+					"memory":  {"r5.large"},
+				} {
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+						cloudprovideraws.Constraints(cloudprovider.Constraints{Preset: preset}))
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf(expected), preset)
+				}
+			})
+			It("should apply the preset's requirements to explicit instance types", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Preset: "compute"})
+				constraints.InstanceTypes = []string{"m5.large", "c5.xlarge"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("c5.xlarge"))
+			})
+			It("should override the preset's memory to vCPU ratio with explicit bounds", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Preset: "compute", MinMemoryMiBPerVCPU: 4096})
+				constraints.InstanceTypes = []string{"r5.large", "c5.xlarge"}
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("r5.large"))
+				Expect(result.Eliminated).Should(HaveKeyWithValue("memoryPerVCPU", 1))
+			})
+			It("should warn about unknown presets", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Preset: "unknown"}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Instances).Should(HaveLen(5))
+				Expect(result.Warnings).Should(ConsistOf(ContainSubstring("unknown")))
+			})
+		})
+
+		Context("With a capacity signal", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "c5.xlarge", "r5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithCapacitySignal(fakeCapacitySignal{
+				"m5.large/" + testZone:  0.9,
+				"c5.xlarge/" + testZone: 0.2,
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should rank instance types by launch failure rate", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"r5.large", "c5.xlarge", "m5.large"}))
+			})
+			It("should exclude instance types that fail to launch too often", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MaxLaunchFailureRate: 0.5}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"r5.large", "c5.xlarge"}))
+			})
+		})
+
+		Context("With a constraint group", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large", "c5.xlarge", "r5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			arm64 := cloudprovider.Constraints{}
+			arm64.Architecture = &v1alpha1.ArchitectureArm64
+			amd64 := cloudprovider.Constraints{}
+			amd64.Architecture = &v1alpha1.ArchitectureAmd64
+			amd64.InstanceTypes = []string{"c5.xlarge", "m6g.large"}
+
+			It("should select instance types satisfying any of the groups", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Group: &cloudprovider.ConstraintGroup{
+						AnyOf: []cloudprovider.ConstraintGroup{
+							{Constraints: &arm64},
+							{Constraints: &amd64},
+						},
+					}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6g.large", "c5.xlarge"))
+			})
+			It("should select instance types satisfying all of the groups", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Group: &cloudprovider.ConstraintGroup{
+						AllOf: []cloudprovider.ConstraintGroup{
+							{Constraints: &arm64},
+							{Constraints: &amd64},
+						},
+					}}))
+				Expect(err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
+				Expect(instanceTypes).Should(BeEmpty())
+			})
+			It("should not apply default instance types to groups that don't name any", func() {
+				x1 := *instanceTypeMocks["m5.large"]
+				x1.InstanceType = aws.String("x1.16xlarge")
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Group: &cloudprovider.ConstraintGroup{
+					Constraints: &cloudprovider.Constraints{MinZones: 1},
+				}})
+				constraints.InstanceTypes = []string{"x1.16xlarge"}
+				instanceTypes, err := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+					{InstanceTypeInfo: x1, Zones: []string{testZone}},
+				}).Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("x1.16xlarge"))
+			})
+			It("should not apply the default architecture to groups that don't set one", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Group: &cloudprovider.ConstraintGroup{
+					Constraints: &cloudprovider.Constraints{MinZones: 1},
+				}})
+				constraints.Architecture = &v1alpha1.ArchitectureArm64
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6g.large"))
+			})
+		})
+
+		Context("With a rotation interval and a launch latency signal", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "c5.xlarge", "r5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithLaunchLatencySignal(fakeLaunchLatencySignal{
+				"m5.large": 20 * time.Minute,
+				"r5.large": 2 * time.Minute,
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should rank instance types that launch slowly last", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RotationInterval: 24 * time.Hour}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)[2]).Should(Equal("m5.large"))
+			})
+		})
+
+		Context("With savings plan or reserved instance commitments", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "c5.xlarge", "r5.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			commitments := &cloudprovider.CommitmentCoverage{InstanceFamilies: []string{"r5"}, InstanceTypes: []string{"c5.xlarge"}}
+
+			It("should order covered instance types first", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Commitments: commitments}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(HaveLen(4))
+				Expect(instanceTypeNames(instanceTypes)[:2]).Should(ConsistOf("c5.xlarge", "r5.large"))
+			})
+			It("should exclude uncovered instance types if required", func() {
+				required := *commitments
+				required.Required = true
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Commitments: &required}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("c5.xlarge", "r5.large"))
+			})
+			It("should not restrict capacity types the commitments don't apply to", func() {
+				required := *commitments
+				required.Required = true
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Commitments: &required})
+				constraints.Labels = map[string]string{"node.k8s.aws/capacity-type": "spot"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("c5.xlarge", "r5.large", "t3.large"))
+			})
+		})
+
+		Context("With EFA required", func() {
+			withEFA := func(name string, supported bool, interfaces int64) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				networkInfo := *instanceTypeInfo.NetworkInfo
+				networkInfo.EfaSupported = aws.Bool(supported)
+				if supported {
+					networkInfo.EfaInfo = &ec2.EfaInfo{MaximumEfaInterfaces: aws.Int64(interfaces)}
+				}
+				instanceTypeInfo.NetworkInfo = &networkInfo
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				withEFA("m5.large", false, 0),
+				withEFA("c5n.18xlarge", true, 1),
+				withEFA("p4d.24xlarge", true, 4),
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			DescribeTable("should only select instance types with enough EFA interfaces",
+				func(required bool, minimum int64, expected ...string) {
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+						cloudprovideraws.Constraints(cloudprovider.Constraints{RequireEFA: required, MinEFAInterfaces: minimum}))
+					expectSelected(instanceTypes, err, expected...)
+				},
+				Entry("unconstrained", false, int64(0), "m5.large", "c5n.18xlarge", "p4d.24xlarge"),
+				Entry("required", true, int64(0), "c5n.18xlarge", "p4d.24xlarge"),
+				Entry("a minimum of one interface", false, int64(1), "c5n.18xlarge", "p4d.24xlarge"),
+				Entry("a minimum of four interfaces", true, int64(4), "p4d.24xlarge"),
+				Entry("a minimum of more interfaces than any instance type supports", true, int64(8)),
+			)
+		})
+
+		Context("With jumbo frames required", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+				{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String("m1.large"),
+					CurrentGeneration:     aws.Bool(false),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+				}, Zones: []string{testZone}},
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{RequireJumboFrames: true}))
+
+			It("should exclude instance types that don't support jumbo frames", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+		})
+
+		Context("With incomplete instance type info", func() {
+			incomplete := func(name string, clear func(*ec2.InstanceTypeInfo)) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				clear(&instanceTypeInfo)
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+				incomplete("", func(info *ec2.InstanceTypeInfo) { info.InstanceType = nil }),
+				incomplete("m5.xlarge", func(info *ec2.InstanceTypeInfo) { info.ProcessorInfo = nil }),
+				incomplete("m5.2xlarge", func(info *ec2.InstanceTypeInfo) { info.VCpuInfo = nil }),
+				incomplete("m5.4xlarge", func(info *ec2.InstanceTypeInfo) { info.MemoryInfo = &ec2.MemoryInfo{} }),
+				incomplete("m5.8xlarge", func(info *ec2.InstanceTypeInfo) { info.NetworkInfo, info.BareMetal, info.EbsInfo = nil, nil, nil }),
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			DescribeTable("should select instance types missing optional fields without panicking",
+				func(constraints cloudprovider.Constraints, expected ...string) {
+					constraints.Pods = []*v1.Pod{test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("100Mi")},
+					}})}
+					var instanceTypes []*packing.Instance
+					var err error
+					Expect(func() {
+						instanceTypes, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(constraints))
+					}).ShouldNot(Panic())
+					expectSelected(instanceTypes, err, expected...)
+				},
+				Entry("unconstrained", cloudprovider.Constraints{}, "m5.large", "m5.8xlarge"),
+				Entry("with IPv6 required", cloudprovider.Constraints{RequireIPv6: true}),
+				Entry("with metal required", cloudprovider.Constraints{RequireMetal: true}),
+				Entry("with EBS encryption required", cloudprovider.Constraints{RequireEBSEncryption: true}, "m5.large"),
+				Entry("with elastic IPs required", cloudprovider.Constraints{MinElasticIPs: 1}, "m5.large"),
+				Entry("with physical cores required", cloudprovider.Constraints{MinPhysicalCores: 1}),
+			)
+			It("should count accelerators without a count as none", func() {
+				instance := incomplete("p3.2xlarge", func(info *ec2.InstanceTypeInfo) {
+					info.GpuInfo = &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{
+						{Manufacturer: aws.String("NVIDIA")},
+						{Manufacturer: aws.String("AMD")},
+					}}
+					info.InferenceAcceleratorInfo = &ec2.InferenceAcceleratorInfo{Accelerators: []*ec2.InferenceDeviceInfo{{Manufacturer: aws.String("AWS")}}}
+				})
+				Expect(func() {
+					Expect(packing.CountNvidiaGPUs(instance)).Should(BeZero())
+					Expect(packing.CountAMDGPUs(instance)).Should(BeZero())
+					Expect(packing.CountAWSNeurons(instance)).Should(BeZero())
+				}).ShouldNot(Panic())
+			})
+			It("should attribute skipped instance types to malformed info", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Eliminated).Should(HaveKeyWithValue("malformed", 1))
+			})
+			It("should explain instance types missing optional fields without panicking", func() {
+				var explanation map[string][]string
+				var err error
+				Expect(func() {
+					explanation, err = instanceTypeProvider.Explain(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				}).ShouldNot(Panic())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(explanation).Should(HaveLen(5))
+				Expect(explanation).Should(HaveKeyWithValue("m5.xlarge", []string{"instanceType"}))
+			})
+			It("should skip instance types without a name when they're discovered", func() {
+				ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = nil
+				ec2api.DescribeInstanceTypesOutput.InstanceTypes = append(ec2api.DescribeInstanceTypesOutput.InstanceTypes, &instanceTypeInfo)
+				instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+		})
+
+		Context("With IPv6 required", func() {
+			withIPv6 := func(name string, zone string, supported bool) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				networkInfo := *instanceTypeInfo.NetworkInfo
+				networkInfo.Ipv6Supported = aws.Bool(supported)
+				instanceTypeInfo.NetworkInfo = &networkInfo
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{zone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				withIPv6("m5.large", "test-zone-1a", true),
+				withIPv6("m5.xlarge", "test-zone-1a", false),
+				withIPv6("m5.2xlarge", "test-zone-1b", true),
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{"test-zone-1a": nil}
+
+			It("should only select IPv6 capable instance types in the zones", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireIPv6: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+			It("should select instance types that don't support IPv6 when not required", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5.xlarge"))
+			})
+			It("should attribute instance types that don't support IPv6 to their own predicate", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{RequireIPv6: true})
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Eliminated).Should(Equal(map[string]int{"ipv6": 1, "zones": 1}))
+				explanations, err := instanceTypeProvider.Explain(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(explanations).Should(HaveKeyWithValue("m5.xlarge", []string{"ipv6"}))
+				Expect(explanations).Should(HaveKeyWithValue("m5.2xlarge", []string{"zones"}))
+				constraints.InstanceTypes = []string{"m5.xlarge"}
+				_, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				noMatching := &cloudprovideraws.NoMatchingInstanceTypesError{}
+				Expect(errors.As(err, &noMatching)).Should(BeTrue())
+				Expect(noMatching.Binding).Should(ContainElement("ipv6"))
+				Expect(noMatching.Binding).ShouldNot(ContainElement("zones"))
+			})
+		})
+
+		Context("With daemonset overhead", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			overheadOf := func(cpu string, pods int64) v1.ResourceList {
+				return v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourcePods: *resource.NewQuantity(pods, resource.DecimalSI)}
+			}
+
+			It("should exclude instance types without room for the pods alongside the overhead", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{
+						Pods: []*v1.Pod{test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+						}})},
+						Overhead: overheadOf("1500m", 1),
+					}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge"))
+			})
+			It("should exclude instance types that the overhead doesn't fit on", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Overhead: overheadOf("3", 1)}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge"))
+			})
+			It("should exclude instance types without pods to spare for the daemonsets", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Overhead: overheadOf("100m", 40)}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge"))
+			})
+		})
+
+		Context("With minimum generations per architecture", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6i.large", "m6g.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should apply each architecture's minimum", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinGenerations: map[string]int{
+						v1alpha1.ArchitectureAmd64: 6,
+						v1alpha1.ArchitectureArm64: 7,
+					}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6i.large"))
+			})
+			It("should not restrict architectures without a minimum", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{MinGenerations: map[string]int{
+					v1alpha1.ArchitectureAmd64: 6,
+				}})
+				constraints.InstanceTypes = []string{"m5.large", "m6i.large", "m6g.large"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6i.large", "m6g.large"))
+			})
+		})
+
+		Context("With GPU requests from different manufacturers", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "g4dn.xlarge", "g4ad.xlarge"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			constraintsRequesting := func(resourceName v1.ResourceName) cloudprovideraws.Constraints {
+				return cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+					test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{resourceName: resource.MustParse("1")},
+						Limits:   v1.ResourceList{resourceName: resource.MustParse("1")},
+					}}),
+				}})
+			}
+
+			It("should only select NVIDIA GPU instance types for NVIDIA GPU requests", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.NvidiaGPU))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("g4dn.xlarge"))
+			})
+			It("should only select AMD GPU instance types for AMD GPU requests", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.AMDGPU))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("g4ad.xlarge"))
+			})
+		})
+
+		Context("With accelerator quantities requested", func() {
+			withAccelerators := func(name string, gpus int64, neurons int64) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				if gpus > 0 {
+					instanceTypeInfo.GpuInfo = &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{
+						{Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(gpus)},
+					}}
+				}
+				if neurons > 0 {
+					instanceTypeInfo.InferenceAcceleratorInfo = &ec2.InferenceAcceleratorInfo{Accelerators: []*ec2.InferenceDeviceInfo{
+						{Manufacturer: aws.String("AWS"), Count: aws.Int64(neurons)},
+					}}
+				}
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			acceleratedInstanceTypes := []*packing.Instance{
+				withAccelerators("g4dn.xlarge", 1, 0),
+				withAccelerators("g4dn.12xlarge", 4, 0),
+				withAccelerators("inf1.xlarge", 0, 1),
+				withAccelerators("inf1.6xlarge", 0, 4),
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(acceleratedInstanceTypes)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			constraintsRequesting := func(resourceName v1.ResourceName, quantities ...string) cloudprovideraws.Constraints {
+				pods := []*v1.Pod{}
+				for _, quantity := range quantities {
+					pods = append(pods, test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{resourceName: resource.MustParse(quantity)},
+						Limits:   v1.ResourceList{resourceName: resource.MustParse(quantity)},
+					}}))
+				}
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: pods})
+				constraints.InstanceTypes = []string{"g4dn.xlarge", "g4dn.12xlarge", "inf1.xlarge", "inf1.6xlarge"}
+				return constraints
+			}
+
+			DescribeTable("should only select instance types with enough accelerators for the largest pod",
+				func(resourceName string, quantities []string, expected []string) {
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+						constraintsRequesting(v1.ResourceName(resourceName), quantities...))
+					expectSelected(instanceTypes, err, expected...)
+				},
+				Entry("an exact fit for NVIDIA GPUs", resources.NvidiaGPU, []string{"4"}, []string{"g4dn.12xlarge"}),
+				Entry("under provisioned NVIDIA GPUs", resources.NvidiaGPU, []string{"8"}, []string{}),
+				Entry("over provisioned NVIDIA GPUs", resources.NvidiaGPU, []string{"1"}, []string{"g4dn.xlarge", "g4dn.12xlarge"}),
+				Entry("several pods requesting NVIDIA GPUs", resources.NvidiaGPU, []string{"1", "2", "1"}, []string{"g4dn.12xlarge"}),
+				Entry("an exact fit for Neuron", resources.AWSNeuron, []string{"4"}, []string{"inf1.6xlarge"}),
+				Entry("under provisioned Neuron", resources.AWSNeuron, []string{"16"}, []string{}),
+				Entry("over provisioned Neuron", resources.AWSNeuron, []string{"1"}, []string{"inf1.xlarge", "inf1.6xlarge"}),
+			)
+			It("should compare GPU requests to the replicas of GPUs shared by time-slicing", func() {
+				instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(acceleratedInstanceTypes).
+					WithGPUTimeSlicing(map[string]int64{"g4dn": 4})
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.NvidiaGPU, "4"))
+				expectSelected(instanceTypes, err, "g4dn.xlarge", "g4dn.12xlarge")
+				instanceTypes, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.NvidiaGPU, "16"))
+				expectSelected(instanceTypes, err, "g4dn.12xlarge")
+				Expect(instanceTypes[0].GPUReplicas).Should(BeNumerically("==", 4))
+				Expect(packing.CountNvidiaGPUs(instanceTypes[0])).Should(BeNumerically("==", 4))
+				Expect(packing.CountSchedulableNvidiaGPUs(instanceTypes[0])).Should(BeNumerically("==", 16))
+				instanceTypes, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.NvidiaGPU, "17"))
+				expectSelected(instanceTypes, err)
+			})
+			It("should only multiply the GPUs of the configured families", func() {
+				instanceTypes, err := cloudprovideraws.NewStaticInstanceTypeProvider(acceleratedInstanceTypes).
+					WithGPUTimeSlicing(map[string]int64{"p3": 4}).
+					Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.NvidiaGPU, "4"))
+				expectSelected(instanceTypes, err, "g4dn.12xlarge")
+				Expect(packing.CountSchedulableNvidiaGPUs(instanceTypes[0])).Should(BeNumerically("==", 4))
+			})
+		})
+
+		Context("With instance types of different sizes", func() {
+			zones := []string{"test-zone-1c", "test-zone-1a", "test-zone-1b"}
+			names := []string{"m5.xlarge", "t3.large", "c5.xlarge", "r5.large", "m5.large"}
+
+			It("should order instance types by vCPUs, then memory, then name on every call", func() {
+				for i := 0; i < 5; i++ {
+					instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(getInstanceTypeProviderMocks(zones, names))
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.large", "t3.large", "r5.large", "c5.xlarge", "m5.xlarge"}))
+					for _, instanceType := range instanceTypes {
+						Expect(instanceType.Zones).Should(Equal([]string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}))
+					}
+				}
+			})
+			It("should sort all instance type names", func() {
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(getInstanceTypeProviderMocks(zones, names))
+				instanceTypeNames, err := instanceTypeProvider.GetAllInstanceTypeNames(context.Background())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames).Should(Equal([]string{"c5.xlarge", "m5.large", "m5.xlarge", "r5.large", "t3.large"}))
+			})
+			It("should list instance type names without retrieving offerings", func() {
+				ec2api := getInstanceTypeProviderMocks(zones, names).(*fake.EC2API)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				for i := 0; i < 2; i++ {
+					instanceTypeNames, err := instanceTypeProvider.GetAllInstanceTypeNames(context.Background())
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(instanceTypeNames).Should(Equal([]string{"c5.xlarge", "m5.large", "m5.xlarge", "r5.large", "t3.large"}))
+				}
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+				Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(BeEmpty())
+			})
+			It("should list the distinct normalized architectures without retrieving offerings", func() {
+				ec2api := getInstanceTypeProviderMocks(zones, []string{"m5.large", "m5.xlarge", "m6g.large", "t3.large"}).(*fake.EC2API)
+				ec2api.DescribeInstanceTypesOutput.InstanceTypes = append(ec2api.DescribeInstanceTypesOutput.InstanceTypes,
+					&ec2.InstanceTypeInfo{
+						InstanceType:  aws.String("a1.large"),
+						ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64", v1alpha1.ArchitectureAmd64})},
+					},
+					&ec2.InstanceTypeInfo{InstanceType: aws.String("x1.unknown")},
+				)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				architectures, err := instanceTypeProvider.GetSupportedArchitectures(context.Background())
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(architectures).Should(Equal([]string{"arm64", "x86_64"}))
+				Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(BeEmpty())
+			})
+			It("should share instance type info with zone aware calls", func() {
+				ec2api := getInstanceTypeProviderMocks(zones, names).(*fake.EC2API)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				_, err := instanceTypeProvider.GetAllInstanceTypeNames(context.Background())
+				Expect(err).ShouldNot(HaveOccurred())
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypes[0].Zones).Should(Equal([]string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+				Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+			})
+		})
+
+		Context("With pods larger than some instance types", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "r5.large", "c5.xlarge", "m5.xlarge"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			constraintsRequesting := func(requests ...v1.ResourceList) cloudprovideraws.Constraints {
+				pods := []*v1.Pod{}
+				for _, request := range requests {
+					pods = append(pods, test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{Requests: request}}))
+				}
+				return cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: pods})
+			}
+
+			It("should exclude instance types smaller than the pod in any dimension", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(
+					v1.ResourceList{v1.ResourceCPU: resource.MustParse("3"), v1.ResourceMemory: resource.MustParse("12Gi")},
+				))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge"))
+			})
+			It("should only exclude instance types smaller than every pod", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(
+					v1.ResourceList{v1.ResourceCPU: resource.MustParse("3")},
+					v1.ResourceList{v1.ResourceMemory: resource.MustParse("12Gi")},
+				))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("r5.large", "c5.xlarge", "m5.xlarge"))
+			})
+			It("should leave pods larger than every instance type for the packer to skip", func() {
+				constraints := constraintsRequesting(
+					v1.ResourceList{v1.ResourceCPU: resource.MustParse("30")},
+					v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				)
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "r5.large", "c5.xlarge", "m5.xlarge"))
+				packings := packing.NewPacker().Pack(context.Background(), constraints.Pods, instanceTypes, &cloudprovider.Constraints{})
+				Expect(packings).Should(HaveLen(1))
+				Expect(packings[0].Pods).Should(Equal([]*v1.Pod{constraints.Pods[1]}))
+			})
+			It("should return an error if every pod is larger than every instance type", func() {
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(
+					v1.ResourceList{v1.ResourceCPU: resource.MustParse("30")},
+					v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Gi")},
+				))
+				Expect(err).Should(MatchError("requests of all 2 pods exceed all 4 allowed instance types"))
+			})
+			It("should return an error if a pod is larger than every instance type", func() {
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(
+					v1.ResourceList{v1.ResourceCPU: resource.MustParse("30"), v1.ResourceMemory: resource.MustParse("1Gi")},
+				))
+				Expect(err).Should(MatchError("pod requests of 30 cpu and 1Gi memory exceed all 4 allowed instance types"))
+			})
+			It("should return an error if a pod is larger than every allowed instance type", func() {
+				constraints := constraintsRequesting(v1.ResourceList{v1.ResourceCPU: resource.MustParse("3")})
+				constraints.InstanceTypes = []string{"m5.large", "r5.large"}
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).Should(MatchError("pod requests of 3 cpu and 0 memory exceed all 2 allowed instance types"))
+			})
+			It("should not return the error if other constraints eliminate the instance types", func() {
+				constraints := constraintsRequesting(v1.ResourceList{v1.ResourceCPU: resource.MustParse("3")})
+				constraints.Architecture = aws.String(v1alpha1.ArchitectureArm64)
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
+				Expect(instanceTypes).Should(BeEmpty())
+			})
+		})
+
+		Context("With excluded instance types and families", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge", "c5.xlarge", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			DescribeTable("should exclude instance types even if they're allowed",
+				func(allowed []string, excludedInstanceTypes []string, excludedFamilies []string, expected []string) {
+					constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{
+						ExcludedInstanceTypes: excludedInstanceTypes,
+						ExcludedFamilies:      excludedFamilies,
+					})
+					constraints.InstanceTypes = allowed
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+					expectSelected(instanceTypes, err, expected...)
+				},
+				Entry("defaults without exclusions", nil, nil, nil, []string{"m5.large", "m5.xlarge", "c5.xlarge", "t3.large"}),
+				Entry("defaults excluding a family", nil, nil, []string{"t3"}, []string{"m5.large", "m5.xlarge", "c5.xlarge"}),
+				Entry("defaults excluding an instance type", nil, []string{"m5.xlarge"}, nil, []string{"m5.large", "c5.xlarge", "t3.large"}),
+				Entry("allowed excluding an overlapping family", []string{"m5.large", "t3.large"}, nil, []string{"t3"}, []string{"m5.large"}),
+				Entry("allowed excluding an overlapping instance type", []string{"m5.large", "c5.xlarge"}, []string{"c5.xlarge"}, nil, []string{"m5.large"}),
+				Entry("allowed excluding a disjoint family", []string{"m5.large"}, nil, []string{"c5"}, []string{"m5.large"}),
+				Entry("allowed entirely excluded", []string{"m5.large", "m5.xlarge"}, nil, []string{"m5"}, []string{}),
+			)
+		})
+
+		Context("With current generation only", func() {
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			currentGeneration := &ec2.Filter{Name: aws.String("current-generation"), Values: []*string{aws.String("true")}}
+
+			It("should filter discovery to current generation instance types", func() {
+				ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{CurrentGenerationOnly: true})
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[0].Filters).Should(ContainElement(currentGeneration))
+			})
+			It("should not filter discovery by generation if unset", func() {
+				ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[0].Filters).Should(ConsistOf(
+					&ec2.Filter{Name: aws.String("supported-virtualization-type"), Values: []*string{aws.String("hvm")}},
+				))
+			})
+			It("should cache current generation instance types separately", func() {
+				ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{CurrentGenerationOnly: true})
+				for i := 0; i < 2; i++ {
+					_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+					Expect(err).ShouldNot(HaveOccurred())
+					_, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+					Expect(err).ShouldNot(HaveOccurred())
+				}
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(2))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[0].Filters).Should(ContainElement(currentGeneration))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[1].Filters).ShouldNot(ContainElement(currentGeneration))
+			})
+		})
+
+		Context("With custom instance type filters", func() {
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			hvm := &ec2.Filter{Name: aws.String("supported-virtualization-type"), Values: []*string{aws.String("hvm")}}
+			nitro := &ec2.Filter{Name: aws.String("hypervisor"), Values: []*string{aws.String("nitro")}}
+			arm64 := &ec2.Filter{Name: aws.String("processor-info.supported-architecture"), Values: []*string{aws.String("arm64")}}
+
+			It("should merge the custom filters with the default filters", func() {
+				ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{InstanceTypeFilters: map[string][]string{
+					"processor-info.supported-architecture": {"arm64"},
+					"hypervisor":                            {"nitro"},
+				}})
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[0].Filters).Should(Equal([]*ec2.Filter{hvm, nitro, arm64}))
+			})
+			It("should cache instance types discovered with different filters separately", func() {
+				ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+				instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+				for i := 0; i < 2; i++ {
+					for _, filters := range []map[string][]string{nil, {"hypervisor": {"nitro"}}, {"hypervisor": {"xen"}}} {
+						_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+							cloudprovideraws.Constraints(cloudprovider.Constraints{InstanceTypeFilters: filters}))
+						Expect(err).ShouldNot(HaveOccurred())
+					}
+				}
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(3))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[0].Filters).Should(ConsistOf(hvm))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[1].Filters).Should(ConsistOf(hvm, nitro))
+				Expect(ec2api.CalledWithDescribeInstanceTypesInput[2].Filters).Should(ConsistOf(hvm,
+					&ec2.Filter{Name: aws.String("hypervisor"), Values: []*string{aws.String("xen")}}))
+			})
+		})
+	})
+
+	Describe("Getting Static Instance Types", func() {
+		instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+			{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+			{InstanceTypeInfo: ec2.InstanceTypeInfo{
+				InstanceType:          aws.String("m5.1000xlarge"),
+				SupportedUsageClasses: []*string{aws.String("on-demand")},
+				BareMetal:             aws.Bool(false),
+				ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+				VCpuInfo:              &ec2.VCpuInfo{DefaultVCpus: aws.Int64(1000)},
+				MemoryInfo:            &ec2.MemoryInfo{SizeInMiB: aws.Int64(4096000)},
+			}, Zones: []string{testZone}},
+		})
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+		It("should filter static instance types without calling EC2", func() {
+			instanceTypes, err := instanceTypeProvider.TopKFit(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+					test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("900")},
+					}}),
+				}}), 1)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.1000xlarge"}))
+		})
+		It("should apply constraints to static instance types", func() {
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+			constraints.InstanceTypes = []string{"m5.large"}
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.large"}))
+		})
+
+		Context("With in-transit encryption required", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6i.large", "c5.xlarge"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{RequireInTransitEncryption: true}))
+
+			It("should exclude instance types that don't encrypt traffic in transit", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6i.large"))
+			})
+		})
+
+		Context("With a tenancy", func() {
+			withDedicatedHosts := func(name string, supported bool) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				instanceTypeInfo.DedicatedHostsSupported = aws.Bool(supported)
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				withDedicatedHosts("m5.large", true),
+				withDedicatedHosts("t3.large", false),
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			DescribeTable("should only select instance types that support the tenancy",
+				func(tenancy string, expected ...string) {
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+						cloudprovideraws.Constraints(cloudprovider.Constraints{Tenancy: tenancy}))
+					expectSelected(instanceTypes, err, expected...)
+				},
+				Entry("unset", "", "m5.large", "t3.large"),
+				Entry("default", "default", "m5.large", "t3.large"),
+				Entry("dedicated", "dedicated", "m5.large", "t3.large"),
+				Entry("host", "host", "m5.large"),
+				Entry("unknown", "shared"),
+			)
+			It("should warn about unknown tenancies", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{Tenancy: "shared"}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Warnings).Should(ContainElement(`unknown tenancy "shared" excludes every instance type`))
+			})
+		})
+
+		Context("With a boot mode", func() {
+			withBootModes := func(name string, bootModes ...string) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				instanceTypeInfo.SupportedBootModes = aws.StringSlice(bootModes)
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				withBootModes("m5.large", "legacy-bios", "uefi"),
+				withBootModes("m6g.large", "uefi"),
+				withBootModes("m4.large", "legacy-bios"),
+				withBootModes("m3.large"),
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			DescribeTable("should only select instance types that support the boot mode",
+				func(bootMode string, expected ...string) {
+					constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{BootMode: bootMode})
+					constraints.InstanceTypes = []string{"m5.large", "m6g.large", "m4.large", "m3.large"}
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+					expectSelected(instanceTypes, err, expected...)
+				},
+				Entry("unset", "", "m5.large", "m6g.large", "m4.large", "m3.large"),
+				Entry("uefi", "uefi", "m5.large", "m6g.large"),
+				Entry("legacy-bios", "legacy-bios", "m5.large", "m4.large"),
+				Entry("unknown", "bios"),
+			)
+			It("should warn about unknown boot modes", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{BootMode: "bios"}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Warnings).Should(ContainElement(`unknown boot mode "bios" excludes every instance type`))
+			})
+		})
+
+		Context("With Nitro required", func() {
+			withHypervisor := func(name string, hypervisor *string, bareMetal bool) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				instanceTypeInfo.Hypervisor = hypervisor
+				instanceTypeInfo.BareMetal = aws.Bool(bareMetal)
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				withHypervisor("m5.large", aws.String("nitro"), false),
+				withHypervisor("m4.large", aws.String("xen"), false),
+				withHypervisor("m5.metal", nil, true),
+				withHypervisor("m5.xlarge", nil, false),
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should only select nitro and bare metal instance types", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{RequireNitro: true})
+				constraints.InstanceTypes = []string{"m5.large", "m4.large", "m5.metal", "m5.xlarge"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5.metal"))
+			})
+			It("should select every hypervisor when not required", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.InstanceTypes = []string{"m5.large", "m4.large", "m5.metal", "m5.xlarge"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m4.large", "m5.metal", "m5.xlarge"))
+			})
+			It("should attribute instance types that aren't nitro to the nitro predicate", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{RequireNitro: true})
+				constraints.InstanceTypes = []string{"m5.large", "m4.large", "m5.metal", "m5.xlarge"}
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Eliminated).Should(Equal(map[string]int{"nitro": 2}))
+			})
+		})
+
+		Context("With burstable instance types excluded or preferred", func() {
+			withBurstable := func(name string, burstable *bool) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(name)
+				instanceTypeInfo.BurstablePerformanceSupported = burstable
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypes := []*packing.Instance{
+				withBurstable("m5.large", aws.Bool(false)),
+				withBurstable("trn1.large", nil),
+				withBurstable("t3.large", aws.Bool(true)),
+				withBurstable("t4g.large", nil),
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			names := []string{"m5.large", "trn1.large", "t3.large", "t4g.large"}
+
+			It("should exclude burstable instance types, falling back to the t category if EC2 doesn't report it", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{ExcludeBurstable: true})
+				constraints.InstanceTypes = names
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("m5.large", "trn1.large"))
+				Expect(result.Eliminated).Should(Equal(map[string]int{"burstable": 2}))
+			})
+			It("should order burstable instance types first without excluding others", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{PreferBurstable: true})
+				constraints.InstanceTypes = names
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypes).Should(HaveLen(4))
+				Expect(instanceTypeNames(instanceTypes[:2])).Should(ConsistOf("t3.large", "t4g.large"))
+			})
+			It("should log the candidates entering and surviving each predicate at debug level", func() {
+				core, logs := observer.New(zap.DebugLevel)
+				defer zap.ReplaceGlobals(zap.New(core))()
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{ExcludeBurstable: true})
+				constraints.InstanceTypes = names
+				// A new provider, since the selection for these constraints is cached by the first spec
+				_, err := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				stages := map[string][]int64{}
+				for _, entry := range logs.FilterMessage("Filtered instance types").All() {
+					fields := entry.ContextMap()
+					stages[fields["predicate"].(string)] = []int64{fields["entering"].(int64), fields["surviving"].(int64)}
+				}
+				Expect(stages).Should(HaveKeyWithValue("instanceType", []int64{4, 4}))
+				Expect(stages).Should(HaveKeyWithValue("burstable", []int64{4, 2}))
+				Expect(stages).Should(HaveKeyWithValue("nitro", []int64{2, 2}))
+			})
+			It("should not log stages above debug level", func() {
+				core, logs := observer.New(zap.InfoLevel)
+				defer zap.ReplaceGlobals(zap.New(core))()
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.InstanceTypes = names
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(logs.FilterMessage("Filtered instance types").Len()).Should(BeZero())
+			})
+		})
+
+		Context("With metal included or required", func() {
+			metalInstanceTypeFor := func(instanceType string, vcpus int64, memory int64) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(true),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					VCpuInfo:              &ec2.VCpuInfo{DefaultVCpus: aws.Int64(vcpus)},
+					MemoryInfo:            &ec2.MemoryInfo{SizeInMiB: aws.Int64(memory)},
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+				metalInstanceTypeFor("m5.metal", 96, 393216),
+				metalInstanceTypeFor("c5.metal", 96, 196608),
+			})
+
+			It("should exclude metal instance types by default", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.large"}))
+			})
+			It("should include metal instance types alongside virtualized ones", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{IncludeMetal: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5.metal", "c5.metal"))
+			})
+			It("should only select metal instance types if required", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireMetal: true, IncludeMetal: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.metal", "c5.metal"))
+			})
+			It("should treat instance types that don't report whether they're bare metal as virtualized", func() {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String("m5.xlarge")
+				instanceTypeInfo.BareMetal = nil
+				instanceTypes, err := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+					{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}},
+					metalInstanceTypeFor("m5.metal", 96, 393216),
+				}).Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge"))
+			})
+			It("should select metal instance types ordered by fit", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireMetal: true, Pods: []*v1.Pod{
+						test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("100Gi")},
+						}}),
+					}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"c5.metal", "m5.metal"}))
+			})
+		})
+
+		Context("With FPGAs required", func() {
+			fpgaInstanceTypeFor := func(instanceType string, fpgas int64) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(instanceType)
+				instanceTypeInfo.FpgaInfo = &ec2.FpgaInfo{Fpgas: []*ec2.FpgaDeviceInfo{{Count: aws.Int64(fpgas), Manufacturer: aws.String("Xilinx")}}}
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+				fpgaInstanceTypeFor("f1.2xlarge", 1),
+				fpgaInstanceTypeFor("f1.16xlarge", 8),
+			})
+
+			It("should exclude FPGA instance types by default", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+			It("should only select FPGA instance types if required", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireFPGA: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("f1.2xlarge", "f1.16xlarge"))
+			})
+			It("should exclude instance types with fewer FPGAs than the minimum", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinFPGAs: 2}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("f1.16xlarge"))
+			})
+		})
+
+		Context("With GPU pods sharing a node", func() {
+			gpuInstanceTypeFor := func(instanceType string, gpus int64) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{{
+						Manufacturer: aws.String("NVIDIA"),
+						Count:        aws.Int64(gpus),
+					}}},
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				gpuInstanceTypeFor("p3.2xlarge", 1),
+				gpuInstanceTypeFor("p3.8xlarge", 4),
+				gpuInstanceTypeFor("p3.16xlarge", 8),
+			})
+			gpuPod := test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{resources.NvidiaGPU: resource.MustParse("2")},
+			}})
+
+			It("should require a GPU per pod if the pods don't request GPUs", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{GPUPodsPerNode: 4}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("p3.8xlarge", "p3.16xlarge"))
+			})
+			It("should require room for each pod's GPU request", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{GPUPodsPerNode: 4, Pods: []*v1.Pod{gpuPod}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("p3.16xlarge"))
+			})
+		})
+
+		Context("With an ordered list of capacity types", func() {
+			instanceTypeFor := func(instanceType string, usageClasses ...string) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: aws.StringSlice(usageClasses),
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				instanceTypeFor("m5.large", "on-demand", "spot"),
+				instanceTypeFor("m5.xlarge", "on-demand"),
+				instanceTypeFor("m5.2xlarge", "spot"),
+			})
+			capacityTypesOf := func(instanceTypes []*packing.Instance) map[string][]string {
+				capacityTypes := map[string][]string{}
+				for _, instanceType := range instanceTypes {
+					capacityTypes[*instanceType.InstanceType] = instanceType.CapacityTypes
+				}
+				return capacityTypes
+			}
+
+			It("should include instance types supporting any of the capacity types, tagged in order of preference", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot", "on-demand"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(capacityTypesOf(instanceTypes)).Should(Equal(map[string][]string{
+					"m5.large":   {"spot", "on-demand"},
+					"m5.xlarge":  {"on-demand"},
+					"m5.2xlarge": {"spot"},
+				}))
+			})
+			It("should only include instance types supporting a single capacity type", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(capacityTypesOf(instanceTypes)).Should(Equal(map[string][]string{
+					"m5.large":   {"spot"},
+					"m5.2xlarge": {"spot"},
+				}))
+			})
+			It("should fall back to the capacity type label", func() {
+				constraints := cloudprovideraws.Constraints{}
+				constraints.Labels = map[string]string{"node.k8s.aws/capacity-type": "spot"}
+				Expect(constraints.GetCapacityType()).Should(Equal("spot"))
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5.2xlarge"))
+			})
+			It("should default to on-demand", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(capacityTypesOf(instanceTypes)).Should(Equal(map[string][]string{
+					"m5.large":  {"on-demand"},
+					"m5.xlarge": {"on-demand"},
+				}))
+			})
+		})
+
+		Context("With spot offered in fewer zones than on-demand", func() {
+			zones := []string{"test-zone-1a", "test-zone-1b", "test-zone-1c"}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{{InstanceTypeInfo: ec2.InstanceTypeInfo{
+				InstanceType:          aws.String("m5.large"),
+				SupportedUsageClasses: aws.StringSlice([]string{"on-demand", "spot"}),
+				BareMetal:             aws.Bool(false),
+				ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+			}, Zones: zones, ZonalCapacityTypes: map[string][]string{
+				"test-zone-1a": {"on-demand", "spot"},
+				"test-zone-1b": {"on-demand"},
+				"test-zone-1c": {"on-demand"},
+			}}})
+			zonalSubnetOptionsFor := func(zones ...string) map[string][]*ec2.Subnet {
+				zonalSubnetOptions := map[string][]*ec2.Subnet{}
+				for _, zone := range zones {
+					zonalSubnetOptions[zone] = nil
+				}
+				return zonalSubnetOptions
+			}
+
+			It("should include the instance type for spot in the zone that offers it", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptionsFor("test-zone-1a", "test-zone-1c"),
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+			It("should exclude the instance type for spot in zones that don't offer it", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptionsFor("test-zone-1b", "test-zone-1c"),
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Instances).Should(BeEmpty())
+				Expect(result.Eliminated).Should(Equal(map[string]int{"capacityType": 1}))
+			})
+			It("should include the instance type for on-demand in every zone", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptionsFor("test-zone-1c"),
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"on-demand"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+			It("should only tag the instance type with capacity types offered in the eligible zones", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptionsFor("test-zone-1b"),
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot", "on-demand"}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypes).Should(HaveLen(1))
+				Expect(instanceTypes[0].CapacityTypes).Should(Equal([]string{"on-demand"}))
+			})
+			It("should fall back to on-demand if allowed and no instance types are offered as spot", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptionsFor("test-zone-1b", "test-zone-1c"),
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot"}, AllowOnDemandFallback: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("m5.large"))
+				Expect(result.Instances[0].CapacityTypes).Should(Equal([]string{"on-demand"}))
+				Expect(result.Instances[0].OnDemandFallback).Should(BeTrue())
+				Expect(result.CapacityType).Should(Equal("on-demand"))
+				Expect(result.Warnings).Should(ContainElement("fell back to on-demand, no instance types could be selected for spot"))
+			})
+			It("should not fall back to on-demand if spot instance types are selected", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptionsFor("test-zone-1a"),
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot"}, AllowOnDemandFallback: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypes).Should(HaveLen(1))
+				Expect(instanceTypes[0].CapacityTypes).Should(Equal([]string{"spot"}))
+				Expect(instanceTypes[0].OnDemandFallback).Should(BeFalse())
+			})
+			It("should not fall back to on-demand by default", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptionsFor("test-zone-1b", "test-zone-1c"),
+					cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot"}}))
+				expectSelected(instanceTypes, err)
+			})
+		})
+
+		Context("With a minimum network bandwidth", func() {
+			instanceTypeFor := func(instanceType string, networkPerformance string) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					NetworkInfo:           &ec2.NetworkInfo{NetworkPerformance: aws.String(networkPerformance)},
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				instanceTypeFor("m5.large", "Up to 10 Gigabit"),
+				instanceTypeFor("m5n.8xlarge", "25 Gigabit"),
+				instanceTypeFor("c5n.18xlarge", "100 Gigabit"),
+				instanceTypeFor("m4.large", "Moderate"),
+			})
+
+			It("should exclude instance types without enough baseline bandwidth", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinNetworkBandwidthGbps: 10}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5n.8xlarge", "c5n.18xlarge"))
+			})
+			It("should not restrict instance types if unset", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5n.8xlarge", "c5n.18xlarge", "m4.large"))
+			})
+		})
+
+		Context("With EBS optimization required", func() {
+			ebsInstanceTypeFor := func(instanceType string, support string, baselineThroughputMBps float64) *packing.Instance {
+				ebsInfo := &ec2.EbsInfo{EbsOptimizedSupport: aws.String(support)}
+				if support != ec2.EbsOptimizedSupportUnsupported {
+					ebsInfo.EbsOptimizedInfo = &ec2.EbsOptimizedInfo{BaselineThroughputInMBps: aws.Float64(baselineThroughputMBps)}
+				}
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					EbsInfo:               ebsInfo,
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				ebsInstanceTypeFor("m5.large", ec2.EbsOptimizedSupportDefault, 81.25),
+				ebsInstanceTypeFor("c4.large", ec2.EbsOptimizedSupportSupported, 62.5),
+				ebsInstanceTypeFor("c1.medium", ec2.EbsOptimizedSupportUnsupported, 0),
+			})
+
+			It("should exclude instance types that don't support EBS optimization", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireEBSOptimization: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "c4.large"))
+			})
+			It("should exclude instance types below the minimum baseline throughput", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireEBSOptimization: true, MinEBSBaselineThroughputMBps: 80}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+			It("should require EBS optimization for a minimum baseline throughput", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinEBSBaselineThroughputMBps: 62.5}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "c4.large"))
+			})
+			It("should not restrict instance types if unset", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "c4.large", "c1.medium"))
+			})
+		})
+
+		Context("With a range of memory", func() {
+			instanceTypeFor := func(instanceType string, memoryMiB int64) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					VCpuInfo:              &ec2.VCpuInfo{DefaultVCpus: aws.Int64(memoryMiB / 4096)},
+					MemoryInfo:            &ec2.MemoryInfo{SizeInMiB: aws.Int64(memoryMiB)},
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				instanceTypeFor("m5.large", 8192),
+				instanceTypeFor("m5.xlarge", 16384),
+				instanceTypeFor("m5.4xlarge", 65536),
+				instanceTypeFor("m5.8xlarge", 131072),
+			})
+
+			DescribeTable("should only select instance types whose memory is within the range",
+				func(minimum int64, maximum int64, expected ...string) {
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+						cloudprovideraws.Constraints(cloudprovider.Constraints{MinMemoryMiB: minimum, MaxMemoryMiB: maximum}))
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf(expected))
+				},
+				Entry("in range", int64(8192), int64(65536), "m5.large", "m5.xlarge", "m5.4xlarge"),
+				Entry("below the minimum", int64(16384), int64(0), "m5.xlarge", "m5.4xlarge", "m5.8xlarge"),
+				Entry("above the maximum", int64(0), int64(16384), "m5.large", "m5.xlarge"),
+				Entry("unset", int64(0), int64(0), "m5.large", "m5.xlarge", "m5.4xlarge", "m5.8xlarge"),
+			)
+			It("should restrict explicit instance types to the range", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{MinMemoryMiB: 8192, MaxMemoryMiB: 65536})
+				constraints.InstanceTypes = []string{"m5.large", "m5.8xlarge"}
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			})
+		})
+
+		Context("With a range of memory per vCPU", func() {
+			instanceTypeFor := func(instanceType string, vcpus int64, memoryMiB int64) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					VCpuInfo:              &ec2.VCpuInfo{DefaultVCpus: aws.Int64(vcpus)},
+					MemoryInfo:            &ec2.MemoryInfo{SizeInMiB: aws.Int64(memoryMiB)},
+				}, Zones: []string{testZone}}
+			}
+			withoutMemory := instanceTypeFor("t3.micro", 2, 0)
+			withoutMemory.MemoryInfo = nil
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				instanceTypeFor("c5.large", 2, 4096),
+				instanceTypeFor("m5.large", 2, 8192),
+				instanceTypeFor("r5.large", 2, 16384),
+				instanceTypeFor("t3.nano", 2, 512),
+				instanceTypeFor("g4dn.xlarge", 0, 16384),
+				withoutMemory,
+			})
+
+			DescribeTable("should only select instance types whose memory per vCPU is within the range",
+				func(minimum int64, maximum int64, expected ...string) {
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+						cloudprovideraws.Constraints(cloudprovider.Constraints{MinMemoryMiBPerVCPU: minimum, MaxMemoryMiBPerVCPU: maximum}))
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf(expected))
+				},
+				Entry("compute optimized", int64(0), int64(2048), "c5.large", "t3.nano"),
+				Entry("general purpose", int64(2048), int64(4096), "c5.large", "m5.large"),
+				Entry("memory optimized", int64(8192), int64(0), "r5.large"),
+				Entry("between 1:2 and 1:8", int64(2048), int64(8192), "c5.large", "m5.large", "r5.large"),
+				Entry("unconstrained", int64(0), int64(0), "c5.large", "m5.large", "r5.large", "t3.nano", "g4dn.xlarge", "t3.micro"),
+			)
+		})
+
+		Context("With a minimum amount of GPU memory", func() {
+			gpuInstanceTypeFor := func(instanceType string, gpus int64, gpuMemoryMiB int64) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					GpuInfo: &ec2.GpuInfo{
+						Gpus: []*ec2.GpuDeviceInfo{{
+							Manufacturer: aws.String("NVIDIA"),
+							Count:        aws.Int64(gpus),
+							MemoryInfo:   &ec2.GpuDeviceMemoryInfo{SizeInMiB: aws.Int64(gpuMemoryMiB / gpus)},
+						}},
+						TotalGpuMemoryInMiB: aws.Int64(gpuMemoryMiB),
+					},
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+				gpuInstanceTypeFor("g4dn.xlarge", 1, 16384),
+				gpuInstanceTypeFor("p3.2xlarge", 1, 16384),
+				gpuInstanceTypeFor("p3.8xlarge", 4, 65536),
+			})
+			gpuPod := test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{resources.NvidiaGPU: resource.MustParse("1")},
+			}})
+
+			It("should exclude instance types whose GPUs have too little memory", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinGPUMemoryMiB: 32768, Pods: []*v1.Pod{gpuPod}}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("p3.8xlarge"))
+			})
+			It("should include instance types with exactly the minimum", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinGPUMemoryMiB: 16384}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("g4dn.xlarge", "p3.2xlarge", "p3.8xlarge"))
+			})
+		})
+
+		Context("With multiple accelerator entries", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String("g9.xlarge"),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{
+						{Manufacturer: aws.String("AMD"), Count: aws.Int64(1)},
+						{Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(1)},
+					}},
+				}, Zones: []string{testZone}},
+				{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String("inf9.xlarge"),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					InferenceAcceleratorInfo: &ec2.InferenceAcceleratorInfo{Accelerators: []*ec2.InferenceDeviceInfo{
+						{Manufacturer: aws.String("Xilinx"), Count: aws.Int64(1)},
+						{Manufacturer: aws.String("AWS"), Count: aws.Int64(1)},
+					}},
+				}, Zones: []string{testZone}},
+			})
+			constraintsRequesting := func(resourceName v1.ResourceName) cloudprovideraws.Constraints {
+				return cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+					test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{resourceName: resource.MustParse("1")},
+					}}),
+				}})
+			}
+
+			It("should match NVIDIA GPUs that aren't the first entry", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.NvidiaGPU))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("g9.xlarge"))
+			})
+			It("should match AWS Neurons that aren't the first entry", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.AWSNeuron))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("inf9.xlarge"))
+			})
+			It("should only count accelerators from the requested manufacturer", func() {
+				instance := &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InferenceAcceleratorInfo: &ec2.InferenceAcceleratorInfo{Accelerators: []*ec2.InferenceDeviceInfo{
+						{Manufacturer: aws.String("Xilinx"), Count: aws.Int64(2)},
+						{Manufacturer: aws.String("AWS"), Count: aws.Int64(4)},
+					}},
+					GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{
+						{Manufacturer: aws.String("AMD"), Count: aws.Int64(1)},
+						{Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(8)},
+					}},
+				}}
+				Expect(packing.CountAWSNeurons(instance)).Should(BeNumerically("==", 4))
+				Expect(packing.CountNvidiaGPUs(instance)).Should(BeNumerically("==", 8))
+				Expect(packing.CountAMDGPUs(instance)).Should(BeNumerically("==", 1))
+			})
+		})
+
+		Context("With local storage required", func() {
+			localStorageInstanceTypeFor := func(instanceType string, supported bool, disks ...*ec2.DiskInfo) *packing.Instance {
+				instance := &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:             aws.String(instanceType),
+					SupportedUsageClasses:    []*string{aws.String("on-demand")},
+					BareMetal:                aws.Bool(false),
+					ProcessorInfo:            &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					InstanceStorageSupported: aws.Bool(supported),
+				}, Zones: []string{testZone}}
+				if len(disks) > 0 {
+					instance.InstanceStorageInfo = &ec2.InstanceStorageInfo{Disks: disks, NvmeSupport: aws.String(ec2.EphemeralNvmeSupportRequired)}
+				}
+				return instance
+			}
+			nvmeDisks := func(count int64, sizeInGB int64) *ec2.DiskInfo {
+				return &ec2.DiskInfo{Count: aws.Int64(count), SizeInGB: aws.Int64(sizeInGB), Type: aws.String(ec2.DiskTypeSsd)}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				localStorageInstanceTypeFor("m5.large", false),
+				localStorageInstanceTypeFor("m5d.large", true, nvmeDisks(1, 75)),
+				localStorageInstanceTypeFor("m5d.4xlarge", true, nvmeDisks(2, 300)),
+				localStorageInstanceTypeFor("m5dn.large", false, nvmeDisks(1, 75)),
+			})
+
+			It("should not restrict instance types if unset", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5d.large", "m5d.4xlarge", "m5dn.large"))
+			})
+			It("should exclude instance types that don't support instance storage", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{RequireLocalStorage: true}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5d.large", "m5d.4xlarge"))
+			})
+			It("should sum the size of every disk towards the minimum", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinLocalStorageGB: 500}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5d.4xlarge"))
+			})
+		})
+
+		Context("With a range of vCPUs per GPU", func() {
+			gpuInstanceTypeFor := func(instanceType string, vcpus int64, gpus int64) *packing.Instance {
+				return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String(instanceType),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					VCpuInfo:              &ec2.VCpuInfo{DefaultVCpus: aws.Int64(vcpus)},
+					GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{{
+						Manufacturer: aws.String("NVIDIA"),
+						Count:        aws.Int64(gpus),
+					}}},
+				}, Zones: []string{testZone}}
+			}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+				gpuInstanceTypeFor("g4dn.xlarge", 4, 1),
+				gpuInstanceTypeFor("p3.8xlarge", 32, 4),
+				gpuInstanceTypeFor("g4dn.12xlarge", 48, 4),
+			})
+
+			It("should exclude instance types outside of the range", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinVCPUsPerGPU: 8, MaxVCPUsPerGPU: 8}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("p3.8xlarge"))
+			})
+			It("should exclude instance types without GPUs", func() {
+				instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{MinVCPUsPerGPU: 1}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("g4dn.xlarge", "p3.8xlarge", "g4dn.12xlarge"))
+			})
+		})
+	})
+
+	Describe("Getting Instance Types With Requirements", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge", "c5.xlarge", "m6g.large", "t3.large"})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+		It("should apply ranges and manufacturers", func() {
+			instanceTypes, err := instanceTypeProvider.GetWithRequirements(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{}),
+				&cloudprovideraws.InstanceRequirements{
+					VCpuCount:        &cloudprovideraws.Int64Range{Min: aws.Int64(4)},
+					CpuManufacturers: aws.StringSlice([]string{"intel"}),
+				})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge", "c5.xlarge"))
+		})
+		It("should exclude instance types matching wildcards", func() {
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+			constraints.Architecture = &v1alpha1.ArchitectureArm64
+			instanceTypes, err := instanceTypeProvider.GetWithRequirements(context.Background(), zonalSubnetOptions, constraints,
+				&cloudprovideraws.InstanceRequirements{
+					ExcludedInstanceTypes: aws.StringSlice([]string{"m5.*", "c5.*"}),
+					BurstablePerformance:  aws.String(cloudprovideraws.RequirementExcluded),
+				})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m6g.large"))
+		})
+		It("should not reuse the selection of other requirements", func() {
+			instanceTypes, err := instanceTypeProvider.GetWithRequirements(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{}), &cloudprovideraws.InstanceRequirements{VCpuCount: &cloudprovideraws.Int64Range{Min: aws.Int64(4)}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge", "c5.xlarge"))
+			instanceTypes, err = instanceTypeProvider.GetWithRequirements(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{}), &cloudprovideraws.InstanceRequirements{VCpuCount: &cloudprovideraws.Int64Range{Max: aws.Int64(2)}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "t3.large"))
+		})
+		It("should exclude zones whose subnets have no available IP addresses", func() {
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+				"m5.large":  {"test-zone-1a"},
+				"m5.xlarge": {"test-zone-1a", "test-zone-1b"},
+			}))
+			instanceTypes, err := instanceTypeProvider.GetWithRequirements(context.Background(), map[string][]*ec2.Subnet{
+				"test-zone-1a": {{SubnetId: aws.String("subnet-1"), AvailableIpAddressCount: aws.Int64(0)}},
+				"test-zone-1b": {{SubnetId: aws.String("subnet-2"), AvailableIpAddressCount: aws.Int64(100)}},
+			}, cloudprovideraws.Constraints(cloudprovider.Constraints{}), &cloudprovideraws.InstanceRequirements{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge"))
+			Expect(instanceTypes[0].Zones).Should(ConsistOf("test-zone-1b"))
+		})
+	})
+
+	Describe("Getting Instance Types By Name", func() {
+		ec2api := getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+			"m5.large":  {"test-zone-1a", "test-zone-1b"},
+			"m6g.large": {"test-zone-1a"},
+		})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+
+		It("should return the instance type with its zones and capacity types, regardless of the default criteria", func() {
+			instanceType, err := instanceTypeProvider.GetByName(context.Background(), "m6g.large")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(aws.StringValue(instanceType.InstanceType)).Should(Equal("m6g.large"))
+			Expect(instanceType.Zones).Should(ConsistOf("test-zone-1a"))
+			Expect(instanceType.CapacityTypes).Should(Equal([]string{"on-demand"}))
+			Expect(aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus)).Should(BeNumerically(">", 0))
+		})
+		It("should fail with the unknown name if the instance type isn't offered", func() {
+			_, err := instanceTypeProvider.GetByName(context.Background(), "m5.xlrge")
+			unknown := &cloudprovideraws.UnknownInstanceTypesError{}
+			Expect(errors.As(err, &unknown)).Should(BeTrue())
+			Expect(unknown.Names).Should(Equal([]string{"m5.xlrge"}))
+		})
+		It("should not modify the cached instance types", func() {
+			instanceType, err := instanceTypeProvider.GetByName(context.Background(), "m5.large")
+			Expect(err).ShouldNot(HaveOccurred())
+			instanceType.Zones = nil
+			instanceType, err = instanceTypeProvider.GetByName(context.Background(), "m5.large")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceType.Zones).Should(ConsistOf("test-zone-1a", "test-zone-1b"))
+		})
+	})
+
+	Describe("Validating Instance Type Names", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large"})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+
+		It("should accept instance types that exist, regardless of the default criteria", func() {
+			Expect(instanceTypeProvider.ValidateInstanceTypes(context.Background(), []string{"m5.large", "m6g.large"})).To(Succeed())
+		})
+		It("should name each unknown instance type", func() {
+			err := instanceTypeProvider.ValidateInstanceTypes(context.Background(), []string{"m5.xlrge", "m5.large", "m6g.lrge"})
+			Expect(err).Should(MatchError("unknown instance types m5.xlrge, m6g.lrge"))
+			unknown := &cloudprovideraws.UnknownInstanceTypesError{}
+			Expect(errors.As(err, &unknown)).Should(BeTrue())
+			Expect(unknown.Names).Should(Equal([]string{"m5.xlrge", "m6g.lrge"}))
+		})
+		It("should fail if instance types can't be retrieved", func() {
+			err := cloudprovideraws.NewInstanceTypeProvider(&fake.EC2API{EC2Behavior: fake.EC2Behavior{
+				WantErr: awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil),
+			}}).ValidateInstanceTypes(context.Background(), []string{"m5.large"})
+			Expect(err).Should(HaveOccurred())
+			Expect(errors.As(err, new(*cloudprovideraws.UnknownInstanceTypesError))).Should(BeFalse())
+		})
+	})
+
+	Describe("Registering Filters", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge", "r5.large"})
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+		approved := cloudprovideraws.InstanceTypeFilterFunc(func(instance *packing.Instance, constraints cloudprovideraws.Constraints, zones []string) bool {
+			return *instance.InstanceType != "m5.xlarge"
+		})
+
+		It("should only select instance types that satisfy the filter", func() {
+			instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithFilter("approved", approved).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "r5.large"))
+		})
+		It("should apply filters after the built-in constraints", func() {
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+			constraints.InstanceTypes = []string{"m5.large", "m5.xlarge"}
+			result, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithFilter("approved", approved).GetResult(context.Background(),
+				zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("m5.large"))
+			Expect(result.Eliminated).Should(Equal(map[string]int{"instanceType": 1, "approved": 1}))
+		})
+		It("should pass the constraints and zones to the filter", func() {
+			var seenZones []string
+			var seenPreset string
+			filter := cloudprovideraws.InstanceTypeFilterFunc(func(instance *packing.Instance, constraints cloudprovideraws.Constraints, zones []string) bool {
+				seenZones, seenPreset = zones, constraints.Preset
+				return true
+			})
+			_, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithFilter("observed", filter).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{Preset: "general"}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(seenZones).Should(Equal([]string{testZone}))
+			Expect(seenPreset).Should(Equal("general"))
+		})
+		It("should apply filters registered after a selection is cached", func() {
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5.xlarge", "r5.large"))
+			instanceTypes, err = instanceTypeProvider.WithFilter("approved", approved).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "r5.large"))
+		})
+		It("should attribute eliminations to the built-in filters by name", func() {
+			explanation, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithFilter("approved", approved).Explain(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{RequireNitro: true}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(explanation["m5.xlarge"]).Should(Equal([]string{"nitro", "approved"}))
+		})
+	})
+
+	Describe("Scoring Instance Types", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge", "r5.large"})
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+		It("should order instance types by a custom scorer, highest first", func() {
+			preferred := map[string]float64{"r5.large": 3, "m5.large": 2, "m5.xlarge": 1}
+			scorer := cloudprovideraws.InstanceTypeScorerFunc(func(instance *packing.Instance) float64 {
+				return preferred[*instance.InstanceType]
+			})
+			instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithScorer(scorer).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"r5.large", "m5.large", "m5.xlarge"}))
+		})
+		It("should pack instance types by score before size", func() {
+			scorer := cloudprovideraws.InstanceTypeScorerFunc(func(instance *packing.Instance) float64 {
+				return float64(aws.Int64Value(instance.VCpuInfo.DefaultVCpus))
+			})
+			pods := []*v1.Pod{test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			}})}
+			unscored, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: pods}))
+			Expect(err).ShouldNot(HaveOccurred())
+			packings := packing.NewPacker().Pack(context.Background(), pods, unscored, &cloudprovider.Constraints{})
+			Expect(packings).Should(HaveLen(1))
+			Expect(instanceTypeNames(packings[0].InstanceTypes)).Should(Equal([]string{"m5.large", "r5.large", "m5.xlarge"}))
+			scored, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithScorer(scorer).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: pods}))
+			Expect(err).ShouldNot(HaveOccurred())
+			packings = packing.NewPacker().Pack(context.Background(), pods, scored, &cloudprovider.Constraints{})
+			Expect(packings).Should(HaveLen(1))
+			Expect(instanceTypeNames(packings[0].InstanceTypes)).Should(Equal([]string{"m5.xlarge", "m5.large", "r5.large"}))
+		})
+		It("should keep the selection order with the default scorer", func() {
+			unscored, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			scored, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithScorer(cloudprovideraws.DefaultScorer).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(scored)).Should(Equal(instanceTypeNames(unscored)))
+		})
+		It("should order instance types by price with the cheapest first scorer, unpriced last", func() {
+			instanceTypeFor := func(instanceType string) *packing.Instance {
+				instanceTypeInfo := *instanceTypeMocks["m5.large"]
+				instanceTypeInfo.InstanceType = aws.String(instanceType)
+				return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+			}
+			prices := fakePriceSource{"on-demand": {"m5.large": 0.096, "m5a.large": 0.086, "m5n.large": 0.119}}
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+			constraints.InstanceTypes = []string{"m5d.large", "m5n.large", "m5.large", "m5a.large"}
+			instanceTypes, err := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				instanceTypeFor("m5d.large"),
+				instanceTypeFor("m5n.large"),
+				instanceTypeFor("m5.large"),
+				instanceTypeFor("m5a.large"),
+			}).WithPriceSource(prices).WithScorer(cloudprovideraws.CheapestFirstScorer).Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5a.large", "m5.large", "m5n.large", "m5d.large"}))
+		})
+	})
+
+	Describe("Ranking Instance Types By Generation", func() {
+		DescribeTable("should extract the generation from the family",
+			func(instanceType string, generation int) {
+				Expect(cloudprovideraws.GenerationOf(instanceType)).Should(Equal(generation))
+			},
+			Entry("a previous generation family", "m4.large", 4),
+			Entry("a family without a processor", "m5.large", 5),
+			Entry("an Intel family", "m6i.large", 6),
+			Entry("a Graviton family", "m6g.large", 6),
+			Entry("an AMD family", "m7a.large", 7),
+			Entry("a family with attributes", "c5dn.xlarge", 5),
+			Entry("a family with a multi-letter category", "inf1.xlarge", 1),
+			Entry("a family with a multi-digit generation", "x10.large", 10),
+			Entry("a family without a generation", "mac.metal", 0),
+		)
+		It("should order newer generations first, otherwise preserving order", func() {
+			instanceTypes := []*packing.Instance{}
+			for _, name := range []string{"m4.large", "m6g.large", "m5.large", "m7a.large", "m6i.large"} {
+				instanceTypes = append(instanceTypes, &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{InstanceType: aws.String(name)}})
+			}
+			Expect(instanceTypeNames(cloudprovideraws.RankByGeneration(instanceTypes))).
+				Should(Equal([]string{"m7a.large", "m6g.large", "m6i.large", "m5.large", "m4.large"}))
+		})
+	})
+
+	Describe("Registering Extended Resources", func() {
+		withEFA := func(name string, efa bool) *packing.Instance {
+			instanceTypeInfo := *instanceTypeMocks["m5.large"]
+			instanceTypeInfo.InstanceType = aws.String(name)
+			instanceTypeInfo.NetworkInfo = &ec2.NetworkInfo{EfaSupported: aws.Bool(efa)}
+			return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+		}
+		instanceTypes := []*packing.Instance{withEFA("m5.large", false), withEFA("c5n.large", true)}
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+		efa := v1.ResourceName("vpc.amazonaws.com/efa")
+		providesEFA := func(instance *packing.Instance, quantity int64) bool {
+			return instance.NetworkInfo != nil && aws.BoolValue(instance.NetworkInfo.EfaSupported)
+		}
+		constraintsRequesting := func(resourceName v1.ResourceName) cloudprovideraws.Constraints {
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+				test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{resourceName: resource.MustParse("1")},
+					Limits:   v1.ResourceList{resourceName: resource.MustParse("1")},
+				}}),
+			}})
+			constraints.InstanceTypes = []string{"m5.large", "c5n.large"}
+			return constraints
+		}
+
+		It("should only select instance types that provide a registered extended resource when it's requested", func() {
+			result, err := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithExtendedResource(efa, "efa", providesEFA).
+				GetResult(context.Background(), zonalSubnetOptions, constraintsRequesting(efa))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("c5n.large"))
+			Expect(result.Eliminated).Should(Equal(map[string]int{"efa": 1}))
+		})
+		It("should not restrict instance types if the extended resource isn't requested", func() {
+			instanceTypes, err := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithExtendedResource(efa, "efa", providesEFA).
+				Get(context.Background(), zonalSubnetOptions, constraintsRequesting(v1.ResourceCPU))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "c5n.large"))
+		})
+		It("should not restrict instance types for extended resources that aren't registered", func() {
+			instanceTypes, err := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).
+				Get(context.Background(), zonalSubnetOptions, constraintsRequesting(efa))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "c5n.large"))
+		})
+		It("should attribute the default accelerators to their own names", func() {
+			result, err := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).
+				GetResult(context.Background(), zonalSubnetOptions, constraintsRequesting(resources.AWSNeuron))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.Eliminated).Should(Equal(map[string]int{"awsNeuron": 2}))
+		})
+	})
+
+	Describe("Explaining Instance Type Selection", func() {
+		ec2api := getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+			"m5.large":    {"test-zone-1a"},
+			"m6g.large":   {"test-zone-1b"},
+			"g4dn.xlarge": {"test-zone-1a"},
+		})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+		zonalSubnetOptions := map[string][]*ec2.Subnet{"test-zone-1a": nil}
+
+		It("should explain every predicate each instance type fails", func() {
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+				test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{resources.NvidiaGPU: resource.MustParse("1")},
+					Limits:   v1.ResourceList{resources.NvidiaGPU: resource.MustParse("1")},
+				}}),
+			}})
+			constraints.Architecture = &v1alpha1.ArchitectureAmd64
+			explanation, err := instanceTypeProvider.Explain(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(explanation).Should(Equal(map[string][]string{
+				"m5.large":    {"nvidiaGPU"},
+				"m6g.large":   {"architecture", "zones", "nvidiaGPU"},
+				"g4dn.xlarge": {},
+			}))
+		})
+		It("should explain no failures for the instance types that are selected", func() {
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+			constraints.Architecture = &v1alpha1.ArchitectureAmd64
+			explanation, err := instanceTypeProvider.Explain(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			selected := []string{}
+			for name, failing := range explanation {
+				if len(failing) == 0 {
+					selected = append(selected, name)
+				}
+			}
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf(selected))
+		})
+	})
+
+	Describe("Getting New Instance Type Names", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large", "m6i.large"})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+
+		It("should return instance types that aren't in the baseline", func() {
+			names, err := instanceTypeProvider.GetNewInstanceTypeNames(context.Background(), []string{"m5.large", "c5.large"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(names).Should(Equal([]string{"m6g.large", "m6i.large"}))
+		})
+	})
+
+	Describe("Getting Instance Types By Zone", func() {
+		ec2api := getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+			"m5.large":  {"test-zone-1a", "test-zone-1b"},
+			"m5.xlarge": {"test-zone-1b", "test-zone-1c"},
+			"r5.large":  {"test-zone-1c"},
+		})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+
+		It("should group instance types offered in overlapping zones", func() {
+			byZone, err := instanceTypeProvider.GetByZone(context.Background(),
+				map[string][]*ec2.Subnet{"test-zone-1a": nil, "test-zone-1b": nil}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(byZone).Should(HaveLen(2))
+			Expect(instanceTypeNames(byZone["test-zone-1a"])).Should(Equal([]string{"m5.large"}))
+			Expect(instanceTypeNames(byZone["test-zone-1b"])).Should(Equal([]string{"m5.large", "m5.xlarge"}))
+		})
+		It("should group instance types offered in disjoint zones", func() {
+			byZone, err := instanceTypeProvider.GetByZone(context.Background(),
+				map[string][]*ec2.Subnet{"test-zone-1a": nil, "test-zone-1c": nil}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(byZone).Should(HaveLen(2))
+			Expect(instanceTypeNames(byZone["test-zone-1a"])).Should(Equal([]string{"m5.large"}))
+			Expect(instanceTypeNames(byZone["test-zone-1c"])).Should(Equal([]string{"r5.large", "m5.xlarge"}))
+		})
+		It("should group instance types by every zone if there are no subnets", func() {
+			byZone, err := instanceTypeProvider.GetByZone(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(byZone).Should(HaveLen(3))
+		})
+		It("should select the same instance types as Get", func() {
+			zonalSubnetOptions := map[string][]*ec2.Subnet{"test-zone-1b": nil, "test-zone-1c": nil}
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{MinMemoryMiB: 16384})
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			byZone, err := instanceTypeProvider.GetByZone(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			grouped := []string{}
+			for _, zonal := range byZone {
+				grouped = append(grouped, instanceTypeNames(zonal)...)
+			}
+			Expect(grouped).Should(ConsistOf("m5.xlarge", "m5.xlarge", "r5.large"))
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.xlarge", "r5.large"))
+		})
+		It("should fail like Get if no instance types satisfy the constraints", func() {
+			_, err := instanceTypeProvider.GetByZone(context.Background(), map[string][]*ec2.Subnet{"test-zone-1d": nil}, cloudprovideraws.Constraints{})
+			Expect(err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
+		})
+	})
+
+	Describe("Getting Instance Types Missing From Zonal Offerings", func() {
+		zonalSubnetOptions := map[string][]*ec2.Subnet{"test-zone-1b": nil}
+		var ec2api *fake.EC2API
+		BeforeEach(func() {
+			ec2api = getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+				"m5.large":  {"test-zone-1a", "test-zone-1b"},
+				"m5.xlarge": {},
+			}).(*fake.EC2API)
+		})
+
+		It("should warn about instance types that aren't offered in any zone", func() {
+			core, logs := observer.New(zap.WarnLevel)
+			defer zap.ReplaceGlobals(zap.New(core))()
+			instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			Expect(logs.FilterMessageSnippet("aren't offered in any availability zone, m5.xlarge").Len()).Should(Equal(1))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+		})
+		It("should fall back to regional offerings, presuming every zone with offerings", func() {
+			ec2api.DescribeRegionalInstanceTypeOfferingsOutput = &ec2.DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: []*ec2.InstanceTypeOffering{
+				{InstanceType: aws.String("m5.large"), Location: aws.String("test-region")},
+				{InstanceType: aws.String("m5.xlarge"), Location: aws.String("test-region")},
+			}}
+			instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithRegionalOfferingsFallback(true).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large", "m5.xlarge"))
+			for _, instanceType := range instanceTypes {
+				Expect(instanceType.Zones).Should(Equal([]string{"test-zone-1a", "test-zone-1b"}))
+			}
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(2))
+			Expect(aws.StringValue(ec2api.CalledWithDescribeInstanceTypeOfferingsInput[1].LocationType)).Should(Equal(ec2.LocationTypeRegion))
+		})
+		It("should exclude instance types that aren't offered in the region either", func() {
+			instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithRegionalOfferingsFallback(true).Get(context.Background(),
+				zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+		})
+	})
+
+	Describe("Getting a Selection Result With Fallback", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large"})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+		preferred := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+		preferred.InstanceTypes = []string{"c6g.large"}
+		fallback := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+		fallback.Architecture = &v1alpha1.ArchitectureArm64
+
+		It("should return the first constraint set that instance types satisfy", func() {
+			result, tier, err := instanceTypeProvider.GetResultWithFallback(context.Background(), zonalSubnetOptions,
+				[]cloudprovideraws.Constraints{preferred, fallback, {}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(tier).Should(Equal(1))
+			Expect(instanceTypeNames(result.Instances)).Should(ConsistOf("m6g.large"))
+		})
+		It("should return the last result if no constraint sets are satisfied", func() {
+			result, tier, err := instanceTypeProvider.GetResultWithFallback(context.Background(), zonalSubnetOptions,
+				[]cloudprovideraws.Constraints{preferred})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(tier).Should(Equal(-1))
+			Expect(result.Instances).Should(BeEmpty())
+		})
+	})
+
+	Describe("Getting Graviton Migration Candidates", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large", "t3.large"})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+		candidates, err := instanceTypeProvider.GetGravitonMigrationCandidates(context.Background(), []string{"m5.large", "t3.large"})
+
+		It("should map x86_64 instance types to their arm64 counterparts", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(candidates).Should(Equal(map[string]string{"m5.large": "m6g.large"}))
+		})
+	})
+
+	Describe("Getting the Top K Best Fit Instance Types", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m5.xlarge", "r5.large"})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+		constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+			test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("4Gi")},
+			}}),
+		}})
+
+		It("should rank instance types by efficiency", func() {
+			instanceTypes, err := instanceTypeProvider.TopKFit(context.Background(), zonalSubnetOptions, constraints, 2)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.large", "r5.large"}))
+		})
+		It("should return all instance types that fit if k is larger", func() {
+			instanceTypes, err := instanceTypeProvider.TopKFit(context.Background(), zonalSubnetOptions, constraints, 10)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.large", "r5.large", "m5.xlarge"}))
+		})
+		It("should exclude instance types that the pods don't fit on", func() {
+			instanceTypes, err := instanceTypeProvider.TopKFit(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+					test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("3")},
+					}}),
+				}}), 3)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.xlarge"}))
+		})
+		It("should rank by the size dimension", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"c5.xlarge", "r5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			for dimension, expected := range map[v1.ResourceName][]string{
+				v1.ResourceCPU:    {"r5.large", "c5.xlarge"},
+				v1.ResourceMemory: {"c5.xlarge", "r5.large"},
+			} {
+				sized := constraints
+				sized.SizeDimension = dimension
+				instanceTypes, err := instanceTypeProvider.TopKFit(context.Background(), zonalSubnetOptions, sized, 2)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypeNames(instanceTypes)).Should(Equal(expected))
+			}
+		})
+		It("should fail if k is not positive", func() {
+			_, err := instanceTypeProvider.TopKFit(context.Background(), zonalSubnetOptions, constraints, 0)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("Getting Diversified Capacity Pools", func() {
+		zones := []string{testZone, "test-zone-2"}
+		ec2api := getInstanceTypeProviderMocks(zones, []string{"m5.large", "m5.xlarge", "c5.xlarge"})
+		instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil, "test-zone-2": nil}
+		constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+			test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			}}),
+		}})
+		poolInstanceTypes := func(pools []cloudprovideraws.CapacityPool) []string {
+			names := []string{}
+			for _, pool := range pools {
+				names = append(names, pool.InstanceType)
+			}
+			return names
+		}
+
+		It("should return every pool that fits", func() {
+			pools, err := instanceTypeProvider.DiversifiedPools(context.Background(), zonalSubnetOptions, constraints, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pools).Should(HaveLen(6))
+			Expect(pools).Should(ContainElement(cloudprovideraws.CapacityPool{InstanceType: "m5.xlarge", Zone: "test-zone-2"}))
+		})
+		It("should alternate between families before truncating", func() {
+			pools, err := instanceTypeProvider.DiversifiedPools(context.Background(), zonalSubnetOptions, constraints, 4)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(poolInstanceTypes(pools)).Should(Equal([]string{"m5.large", "m5.large", "c5.xlarge", "c5.xlarge"}))
+		})
+	})
+
+	Describe("Parsing Network Performance", func() {
+		DescribeTable("should normalize network performance to baseline and burst Gbps",
+			func(networkPerformance string, baselineGbps float64, burstGbps float64) {
+				baseline, burst, err := packing.ParseNetworkPerformance(networkPerformance)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(baseline).Should(BeNumerically("==", baselineGbps))
+				Expect(burst).Should(BeNumerically("==", burstGbps))
+			},
+			Entry("burstable", "Up to 10 Gigabit", 0.0, 10.0),
+			Entry("sustained", "25 Gigabit", 25.0, 25.0),
+			Entry("high bandwidth", "100 Gigabit", 100.0, 100.0),
+			Entry("multiple network cards", "4x 100 Gigabit", 400.0, 400.0),
+			Entry("fractional", "Up to 12.5 Gigabit", 0.0, 12.5),
+		)
+		It("should return an error for qualitative network performance", func() {
+			_, _, err := packing.ParseNetworkPerformance("Moderate")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("Computing Max Pods", func() {
+		instanceTypeFor := func(instanceType string, vCPUs int64, networkInterfaces int64, addressesPerInterface int64, prefixDelegation bool) *packing.Instance {
+			return &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+				InstanceType: aws.String(instanceType),
+				VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(vCPUs)},
+				NetworkInfo: &ec2.NetworkInfo{
+					MaximumNetworkInterfaces:  aws.Int64(networkInterfaces),
+					Ipv4AddressesPerInterface: aws.Int64(addressesPerInterface),
+				},
+			}, PrefixDelegation: prefixDelegation}
+		}
+		DescribeTable("should bound pods by the addresses or prefixes of each network interface",
+			func(instance *packing.Instance, maxPods int64) {
+				Expect(instance.MaxPods()).Should(Equal(maxPods))
+			},
+			Entry("t3.micro", instanceTypeFor("t3.micro", 2, 2, 2, false), int64(4)),
+			Entry("m5.large", instanceTypeFor("m5.large", 2, 3, 10, false), int64(29)),
+			Entry("m5.xlarge", instanceTypeFor("m5.xlarge", 4, 4, 15, false), int64(58)),
+			Entry("c5.18xlarge", instanceTypeFor("c5.18xlarge", 72, 15, 50, false), int64(737)),
+			Entry("t3.micro with prefix delegation", instanceTypeFor("t3.micro", 2, 2, 2, true), int64(34)),
+			Entry("m5.large with prefix delegation", instanceTypeFor("m5.large", 2, 3, 10, true), int64(110)),
+			Entry("c5.18xlarge with prefix delegation", instanceTypeFor("c5.18xlarge", 72, 15, 50, true), int64(250)),
+		)
+		It("should not fit more pods on a node than it has addresses for", func() {
+			pods := []*v1.Pod{}
+			for i := 0; i < 30; i++ {
+				pods = append(pods, test.PendingPod())
+			}
+			instance := instanceTypeFor("m5.large", 2, 3, 10, false)
+			instance.MemoryInfo = &ec2.MemoryInfo{SizeInMiB: aws.Int64(8192)}
+			packings := packing.NewPacker().Pack(context.Background(), pods, []*packing.Instance{instance}, &cloudprovider.Constraints{})
+			Expect(packings).Should(HaveLen(2))
+			Expect(packings[0].Pods).Should(HaveLen(29))
+		})
+		It("should enable prefix delegation on selected instance types", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+			instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithPrefixDelegation(true).Get(context.Background(),
+				map[string][]*ec2.Subnet{testZone: nil}, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypes).Should(HaveLen(1))
+			Expect(instanceTypes[0].PrefixDelegation).Should(BeTrue())
+			Expect(instanceTypes[0].MaxPods()).Should(Equal(int64(110)))
+		})
+	})
+
+	Describe("Getting Instance Type Labels", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+		instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Get(context.Background(),
+			map[string][]*ec2.Subnet{testZone: nil}, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+
+		It("should derive valid labels from the instance type info", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypes).Should(HaveLen(1))
+			Expect(instanceTypes[0].Labels()).Should(Equal(map[string]string{
+				packing.InstanceFamilyLabelKey:             "m5",
+				packing.InstanceSizeLabelKey:               "large",
+				packing.InstanceHypervisorLabelKey:         "nitro",
+				packing.InstanceNetworkPerformanceLabelKey: "Up-to-10-Gigabit",
+				packing.InstanceLocalStorageLabelKey:       "false",
+				v1alpha1.ArchitectureLabelKey:              v1alpha1.ArchitectureAmd64,
+			}))
+		})
+	})
+
+	Describe("Getting the Discovery Version", func() {
+		It("should increment when instance types are discovered", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			Expect(instanceTypeProvider.Version()).Should(BeZero())
+			_, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeProvider.Version()).Should(Equal(uint64(1)))
+			_, err = instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeProvider.Version()).Should(Equal(uint64(1)))
+		})
+	})
+
+	Describe("Caching Selections", func() {
+		var filtered int
+		counting := cloudprovideraws.InstanceTypeFilterFunc(func(*packing.Instance, cloudprovideraws.Constraints, []string) bool {
+			filtered++
+			return true
+		})
+		BeforeEach(func() {
+			filtered = 0
+		})
+
+		It("should not filter again for identical constraints and zones", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithFilter("counting", counting)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			Expect(filtered).Should(Equal(1))
+			instanceTypes[0].Price = 1
+			instanceTypes, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			Expect(instanceTypes[0].Price).Should(BeZero())
+			Expect(filtered).Should(Equal(1))
+		})
+		It("should filter again if the constraints or zones change", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone, "test-zone-1b"}, []string{"m5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithFilter("counting", counting)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{RequireNitro: true})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(filtered).Should(Equal(2))
+			_, err = instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{"test-zone-1b": nil}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(filtered).Should(Equal(3))
+		})
+		It("should share the selection of pods with identical requests", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithFilter("counting", counting)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			requesting := func(cpu string) cloudprovideraws.Constraints {
+				return cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{test.PendingPodWith(test.PodOptions{
+					ResourceRequirements: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}},
+				})}})
+			}
+			for _, cpu := range []string{"1", "1", "2"} {
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, requesting(cpu))
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			Expect(filtered).Should(Equal(2))
+		})
+		It("should filter again once the instance types are rediscovered", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithCacheTTLs(0, 0).WithFilter("counting", counting)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeProvider.Version()).Should(Equal(uint64(2)))
+			Expect(filtered).Should(Equal(2))
+		})
+	})
+
+	Describe("Warming the Cache", func() {
+		It("should serve the following calls from the cache", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			Expect(instanceTypeProvider.Warm(context.Background())).Should(Succeed())
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+			ec2api.CalledWithDescribeInstanceTypesInput = nil
+			ec2api.CalledWithDescribeInstanceTypeOfferingsInput = nil
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(BeEmpty())
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(BeEmpty())
+		})
+		It("should return an error if the context is cancelled", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			Expect(cloudprovideraws.NewInstanceTypeProvider(ec2api).Warm(ctx)).Should(MatchError(context.Canceled))
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(BeEmpty())
+		})
+		It("should return an error if instance types can't be discovered", func() {
+			ec2api := &fake.EC2API{EC2Behavior: fake.EC2Behavior{WantErr: fmt.Errorf("unauthorized")}}
+			Expect(cloudprovideraws.NewInstanceTypeProvider(ec2api).Warm(context.Background())).Should(HaveOccurred())
+		})
+	})
+
+	Describe("Pricing Instance Types", func() {
+		instanceTypeFor := func(instanceType string, usageClasses ...string) *packing.Instance {
+			instanceTypeInfo := *instanceTypeMocks["m5.large"]
+			instanceTypeInfo.InstanceType = aws.String(instanceType)
+			instanceTypeInfo.SupportedUsageClasses = aws.StringSlice(usageClasses)
+			return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+		}
+		instanceTypes := []*packing.Instance{
+			instanceTypeFor("m5.large", "on-demand", "spot"),
+			instanceTypeFor("m5a.large", "on-demand", "spot"),
+			instanceTypeFor("m5n.large", "on-demand"),
+		}
+		prices := fakePriceSource{
+			"on-demand": {"m5.large": 0.096, "m5a.large": 0.086, "m5n.large": 0.119},
+			"spot":      {"m5.large": 0.038, "m5a.large": 0.035},
+		}
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+		constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{
+			CapacityTypes: []string{"spot", "on-demand"},
+			Pods: []*v1.Pod{test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			}})},
+		})
+		pricesOf := func(instanceTypes []*packing.Instance) map[string]float64 {
+			prices := map[string]float64{}
+			for _, instanceType := range instanceTypes {
+				prices[*instanceType.InstanceType] = instanceType.Price
+			}
+			return prices
+		}
+
+		It("should price each instance type's most preferred capacity type", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(prices)
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pricesOf(selected)).Should(Equal(map[string]float64{"m5.large": 0.038, "m5a.large": 0.035, "m5n.large": 0.119}))
+		})
+		It("should break ties between equally sized instance types by price when packing", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(prices)
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			packings := packing.NewPacker().Pack(context.Background(), constraints.Pods, selected, &cloudprovider.Constraints{})
+			Expect(packings).Should(HaveLen(1))
+			Expect(instanceTypeNames(packings[0].InstanceTypes)).Should(Equal([]string{"m5a.large", "m5.large", "m5n.large"}))
+		})
+		It("should select instance types without prices if they can't be retrieved", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(fakePriceSource{})
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pricesOf(selected)).Should(Equal(map[string]float64{"m5.large": 0, "m5a.large": 0, "m5n.large": 0}))
+			packings := packing.NewPacker().Pack(context.Background(), constraints.Pods, selected, &cloudprovider.Constraints{})
+			Expect(packings).Should(HaveLen(1))
+			Expect(instanceTypeNames(packings[0].InstanceTypes)).Should(Equal([]string{"m5.large", "m5a.large", "m5n.large"}))
+		})
+		It("should price the capacity types whose prices can be retrieved", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(fakePriceSource{"on-demand": prices["on-demand"]})
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pricesOf(selected)).Should(Equal(map[string]float64{"m5.large": 0, "m5a.large": 0, "m5n.large": 0.119}))
+		})
+		It("should not retrieve prices again soon after failing to", func() {
+			source := &countingPriceSource{prices: fakePriceSource{"on-demand": prices["on-demand"]}}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(source)
+			for i := 0; i < 3; i++ {
+				_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			Expect(source.calls).Should(Equal(map[string]int{"spot": 1, "on-demand": 1}))
+		})
+		It("should retrieve prices once for concurrent selections", func() {
+			source := &countingPriceSource{prices: prices, delay: 50 * time.Millisecond}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(source)
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(pricesOf(selected)).Should(Equal(map[string]float64{"m5.large": 0.038, "m5a.large": 0.035, "m5n.large": 0.119}))
+				}()
+			}
+			wg.Wait()
+			Expect(source.calls).Should(Equal(map[string]int{"spot": 1, "on-demand": 1}))
+		})
+		It("should price each vCPU and GiB of memory of the selected instance types", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(prices)
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(selected).Should(HaveLen(3))
+			for _, instanceType := range selected {
+				switch *instanceType.InstanceType {
+				case "m5.large":
+					Expect(instanceType.PricePerVCPU()).Should(BeNumerically("~", 0.019, 1e-9))
+					Expect(instanceType.PricePerGiB()).Should(BeNumerically("~", 0.00475, 1e-9))
+				case "m5n.large":
+					Expect(instanceType.PricePerVCPU()).Should(BeNumerically("~", 0.0595, 1e-9))
+					Expect(instanceType.PricePerGiB()).Should(BeNumerically("~", 0.014875, 1e-9))
+				}
+			}
+		})
+		It("should not price the vCPUs or memory of instance types without them", func() {
+			instanceType := &packing.Instance{InstanceTypeInfo: ec2.InstanceTypeInfo{
+				InstanceType: aws.String("m5.large"),
+				VCpuInfo:     &ec2.VCpuInfo{DefaultVCpus: aws.Int64(0)},
+			}, Price: 0.096}
+			Expect(instanceType.PricePerVCPU()).Should(BeZero())
+			Expect(instanceType.PricePerGiB()).Should(BeZero())
+		})
+		It("should return the cheapest instance type and its price", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(prices)
+			name, price, err := instanceTypeProvider.Cheapest(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(name).Should(Equal("m5a.large"))
+			Expect(price).Should(Equal(0.035))
+		})
+		It("should price the cheapest instance type for the preferred capacity type", func() {
+			onDemand := constraints
+			onDemand.CapacityTypes = []string{"on-demand"}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(prices)
+			name, price, err := instanceTypeProvider.Cheapest(context.Background(), zonalSubnetOptions, onDemand)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(name).Should(Equal("m5a.large"))
+			Expect(price).Should(Equal(0.086))
+		})
+		It("should fail if no instance types satisfy the constraints", func() {
+			overConstrained := constraints
+			overConstrained.InstanceTypes = []string{"c5.large"}
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(prices)
+			_, _, err := instanceTypeProvider.Cheapest(context.Background(), zonalSubnetOptions, overConstrained)
+			Expect(err).Should(MatchError("no instance types satisfy the constraints"))
+		})
+		It("should only consider instance types offered in zones with available IP addresses", func() {
+			exhausted := constraints
+			exhausted.MinSubnetAvailableIPs = 10
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(prices)
+			_, _, err := instanceTypeProvider.Cheapest(context.Background(), map[string][]*ec2.Subnet{
+				testZone: {{SubnetId: aws.String("subnet-1"), AvailableIpAddressCount: aws.Int64(5)}},
+			}, exhausted)
+			Expect(err).Should(MatchError("no instance types satisfy the constraints"))
+		})
+		It("should fail if no instance types are priced", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithPriceSource(fakePriceSource{})
+			_, _, err := instanceTypeProvider.Cheapest(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).Should(MatchError("none of the 3 instance types that satisfy the constraints are priced"))
+		})
+		It("should fail without a price source", func() {
+			_, _, err := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).Cheapest(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("Scoring Interruption Frequencies", func() {
+		instanceTypeFor := func(instanceType string, usageClasses ...string) *packing.Instance {
+			instanceTypeInfo := *instanceTypeMocks["m5.large"]
+			instanceTypeInfo.InstanceType = aws.String(instanceType)
+			instanceTypeInfo.SupportedUsageClasses = aws.StringSlice(usageClasses)
+			return &packing.Instance{InstanceTypeInfo: instanceTypeInfo, Zones: []string{testZone}}
+		}
+		instanceTypes := []*packing.Instance{
+			instanceTypeFor("m5.large", "on-demand", "spot"),
+			instanceTypeFor("m5a.large", "on-demand", "spot"),
+			instanceTypeFor("m5d.large", "on-demand", "spot"),
+			instanceTypeFor("m5n.large", "on-demand"),
+		}
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+		constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{
+			CapacityTypes: []string{"spot", "on-demand"},
+			Pods: []*v1.Pod{test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			}})},
+		})
+		scoresOf := func(instanceTypes []*packing.Instance) map[string]float64 {
+			scores := map[string]float64{}
+			for _, instanceType := range instanceTypes {
+				scores[*instanceType.InstanceType] = instanceType.InterruptionScore
+			}
+			return scores
+		}
+
+		DescribeTable("should map interruption frequency ratings to scores",
+			func(rating string, expected float64) {
+				Expect(cloudprovideraws.InterruptionScoreOf(rating)).Should(Equal(expected))
+			},
+			Entry("less than 5%", "<5%", 0.0),
+			Entry("5 to 10%", "5-10%", 0.25),
+			Entry("10 to 15%", "10-15%", 0.5),
+			Entry("15 to 20%", "15-20%", 0.75),
+			Entry("more than 20%", ">20%", 1.0),
+			Entry("unrated", "", 0.5),
+		)
+		It("should score instance types whose most preferred capacity type is spot", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).
+				WithInterruptionSource(fakeInterruptionSource{"m5.large": ">20%", "m5a.large": "<5%", "m5n.large": "<5%"})
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(scoresOf(selected)).Should(Equal(map[string]float64{"m5.large": 1, "m5a.large": 0, "m5d.large": 0.5, "m5n.large": 0}))
+		})
+		It("should break ties between equally sized and priced instance types by score when packing", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes[:3]).
+				WithInterruptionSource(fakeInterruptionSource{"m5.large": ">20%", "m5a.large": "<5%", "m5d.large": "5-10%"})
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			packings := packing.NewPacker().Pack(context.Background(), constraints.Pods, selected, &cloudprovider.Constraints{})
+			Expect(packings).Should(HaveLen(1))
+			Expect(instanceTypeNames(packings[0].InstanceTypes)).Should(Equal([]string{"m5a.large", "m5d.large", "m5.large"}))
+		})
+		It("should score instance types as neutral if ratings can't be retrieved", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider(instanceTypes).WithInterruptionSource(fakeInterruptionSource(nil))
+			selected, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, constraints)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(scoresOf(selected)).Should(Equal(map[string]float64{"m5.large": 0.5, "m5a.large": 0.5, "m5d.large": 0.5, "m5n.large": 0}))
+		})
+		It("should retrieve ratings of Linux instance types in the region from the spot advisor data", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{
+					"ranges": [{"index": 0, "label": "<5%"}, {"index": 4, "label": ">20%"}],
+					"spot_advisor": {
+						"us-west-2": {"Linux": {"m5.large": {"s": 70, "r": 0}, "m5a.large": {"s": 72, "r": 4}}, "Windows": {"m5d.large": {"s": 70, "r": 0}}},
+						"us-east-1": {"Linux": {"m5d.large": {"s": 70, "r": 4}}}
+					}
+				}`)
+			}))
+			defer server.Close()
+			ratings, err := cloudprovideraws.NewAWSInterruptionSource(server.Client(), server.URL).InterruptionFrequencies(context.Background(), "us-west-2")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ratings).Should(Equal(map[string]string{"m5.large": "<5%", "m5a.large": ">20%"}))
+		})
+	})
+
+	Describe("Caching Instance Types", func() {
+		It("should describe offerings again once the cache expires", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithCache(time.Millisecond, time.Millisecond)
+			_, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			time.Sleep(5 * time.Millisecond)
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(2))
+		})
+		It("should isolate the instance types of providers for different regions", func() {
+			east := cloudprovideraws.NewInstanceTypeProvider(getInstanceTypeProviderMocks([]string{"us-east-1a"}, []string{"m5.large", "m5.xlarge"}))
+			west := cloudprovideraws.NewInstanceTypeProvider(getInstanceTypeProviderMocks([]string{"us-west-2a"}, []string{"m5.large", "r5.large"}))
+			eastInstanceTypes, err := east.Get(context.Background(), map[string][]*ec2.Subnet{"us-east-1a": nil}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			westInstanceTypes, err := west.Get(context.Background(), map[string][]*ec2.Subnet{"us-west-2a": nil}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(eastInstanceTypes)).Should(ConsistOf("m5.large", "m5.xlarge"))
+			Expect(instanceTypeNames(westInstanceTypes)).Should(ConsistOf("m5.large", "r5.large"))
+			for _, instanceType := range westInstanceTypes {
+				Expect(instanceType.Zones).Should(Equal([]string{"us-west-2a"}))
+			}
+			names, err := east.GetAllInstanceTypeNames(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(names).Should(ConsistOf("m5.large", "m5.xlarge"))
+		})
+		It("should keep serving static instance types", func() {
+			instanceTypes, err := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+			}).WithCache(time.Millisecond, time.Millisecond).Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+		})
+		It("should refresh stale zonal offerings without retrieving instance type info again", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithCacheTTLs(time.Hour, 10*time.Millisecond)
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypes).Should(HaveLen(1))
+			Expect(instanceTypes[0].Zones).Should(ConsistOf(testZone))
+
+			ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings = append(ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings,
+				&ec2.InstanceTypeOffering{InstanceType: aws.String("m5.large"), Location: aws.String("test-zone-2")})
+			time.Sleep(20 * time.Millisecond)
+			instanceTypes, err = instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypes).Should(HaveLen(1))
+			Expect(instanceTypes[0].Zones).Should(ConsistOf(testZone, "test-zone-2"))
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(2))
+		})
+		It("should not retrieve either while both are cached", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			for i := 0; i < 2; i++ {
+				_, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{}, cloudprovideraws.Constraints{})
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+		})
+	})
+
+	Describe("Getting Allocatable Instance Types", func() {
+		ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.xlarge"})
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+		allocatableFor := func(systemReserved cloudprovideraws.Reserved, kubeReserved cloudprovideraws.Reserved) *packing.Instance {
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithReserved(systemReserved, kubeReserved)
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			allocatable := instanceTypeProvider.Allocatable(instanceTypes)
+			Expect(allocatable).Should(HaveLen(1))
+			Expect(*instanceTypes[0].MemoryInfo.SizeInMiB).Should(BeNumerically("==", 16384))
+			Expect(*instanceTypes[0].VCpuInfo.DefaultVCpus).Should(BeNumerically("==", 4))
+			return allocatable[0]
+		}
+
+		It("should reserve a flat memory reservation", func() {
+			allocatable := allocatableFor(cloudprovideraws.Reserved{}, cloudprovideraws.Reserved{Memory: resource.MustParse("1Gi")})
+			Expect(allocatable.Reserved.Memory().Value()).Should(BeNumerically("==", 1024*1024*1024))
+			Expect(allocatable.Reserved.Cpu().IsZero()).Should(BeTrue())
+		})
+		It("should reserve a percentage of memory in addition to flat reservations", func() {
+			allocatable := allocatableFor(
+				cloudprovideraws.Reserved{Memory: resource.MustParse("512Mi")},
+				cloudprovideraws.Reserved{Memory: resource.MustParse("512Mi"), MemoryPercent: 25},
+			)
+			Expect(allocatable.Reserved.Memory().Value()).Should(BeNumerically("==", 5*1024*1024*1024))
+		})
+		It("should reserve CPU in millicores", func() {
+			allocatable := allocatableFor(cloudprovideraws.Reserved{CPU: resource.MustParse("100m")}, cloudprovideraws.Reserved{CPU: resource.MustParse("1")})
+			Expect(allocatable.Reserved.Cpu().MilliValue()).Should(BeNumerically("==", 1100))
+		})
+		It("should leave the instance type's vCPUs, memory and max pods as they are", func() {
+			allocatable := allocatableFor(cloudprovideraws.Reserved{CPU: resource.MustParse("1")}, cloudprovideraws.Reserved{Memory: resource.MustParse("1Gi")})
+			Expect(*allocatable.VCpuInfo.DefaultVCpus).Should(BeNumerically("==", 4))
+			Expect(*allocatable.MemoryInfo.SizeInMiB).Should(BeNumerically("==", 16384))
+			Expect(allocatable.MaxPods()).Should(BeNumerically("==", 58))
+		})
+		It("should not reserve anything if nothing is reserved", func() {
+			allocatable := allocatableFor(cloudprovideraws.Reserved{}, cloudprovideraws.Reserved{})
+			Expect(allocatable.Reserved).Should(BeNil())
+		})
+		It("should pack pods around the reservation", func() {
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).
+				WithReserved(cloudprovideraws.Reserved{CPU: resource.MustParse("500m")}, cloudprovideraws.Reserved{CPU: resource.MustParse("250m")})
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			pods := []*v1.Pod{}
+			for i := 0; i < 4; i++ {
+				pods = append(pods, test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				}}))
+			}
+			packings := packing.NewPacker().Pack(context.Background(), pods, instanceTypeProvider.Allocatable(instanceTypes), &cloudprovider.Constraints{})
+			Expect(packings).Should(HaveLen(2))
+			Expect(packings[0].Pods).Should(HaveLen(3))
+		})
+	})
+
+	Describe("Exporting the Catalog", func() {
+		It("should export every instance type sorted by name", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{"test-zone-2", testZone}, []string{"t3.large", "m5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			output, err := instanceTypeProvider.ExportCatalog(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			catalog := cloudprovideraws.Catalog{}
+			Expect(json.Unmarshal(output, &catalog)).To(Succeed())
+			Expect(catalog.Version).Should(Equal(cloudprovideraws.CatalogVersion))
+			Expect(catalog.InstanceTypes).Should(HaveLen(2))
+			entry := catalog.InstanceTypes[0]
+			Expect(entry.Name).Should(Equal("m5.large"))
+			Expect(entry.Family).Should(Equal("m5"))
+			Expect(entry.VCPUs).Should(Equal(int64(2)))
+			Expect(entry.MemoryMiB).Should(Equal(int64(8192)))
+			Expect(entry.Zones).Should(Equal([]string{testZone, "test-zone-2"}))
+			Expect(entry.EBSEncryptionSupported).Should(BeTrue())
+			Expect(catalog.InstanceTypes[1].CapacityTypes).Should(Equal([]string{"on-demand", "spot"}))
+		})
+	})
+
+	Describe("Selecting From an Offline Catalog", func() {
+		catalog, err := ioutil.ReadFile("testdata/offline-catalog.json")
+		if err != nil {
+			panic(err)
+		}
+
+		It("should select from the catalog's instance types and offerings", func() {
+			instanceTypeProvider, err := cloudprovideraws.NewOfflineInstanceTypeProvider(catalog)
+			Expect(err).ShouldNot(HaveOccurred())
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{"us-west-2b": nil},
+				cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(Equal([]string{"m5.large", "m5.xlarge"}))
+			instanceTypes, err = instanceTypeProvider.Get(context.Background(), map[string][]*ec2.Subnet{"us-west-2b": nil},
+				cloudprovideraws.Constraints(cloudprovider.Constraints{CapacityTypes: []string{"spot"}}))
+			expectSelected(instanceTypes, err, "m5.xlarge")
+		})
+		It("should select the same instance types as the provider the catalog was exported from", func() {
+			ec2api := getInstanceTypeProviderMocksWithOfferings(map[string][]string{
+				"m5.large":  {"test-zone-1a", "test-zone-1b"},
+				"m6g.large": {"test-zone-1a"},
+				"t3.large":  {"test-zone-1b"},
+			}).(*fake.EC2API)
+			connected := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			exported, err := connected.ExportOfflineCatalog(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			offline, err := cloudprovideraws.NewOfflineInstanceTypeProvider(exported)
+			Expect(err).ShouldNot(HaveOccurred())
+			for _, zonalSubnetOptions := range []map[string][]*ec2.Subnet{{}, {"test-zone-1a": nil}, {"test-zone-1b": nil}} {
+				expected, err := connected.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				instanceTypes, err := offline.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instanceTypes).Should(Equal(expected))
+			}
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+		})
+		It("should fail to load catalogs of other versions", func() {
+			_, err := cloudprovideraws.NewOfflineInstanceTypeProvider([]byte(`{"version": "v0", "instanceTypes": []}`))
+			Expect(err).Should(MatchError(`unsupported offline catalog version "v0", expected "v1"`))
+		})
+		It("should fail to load entries without an instance type", func() {
+			_, err := cloudprovideraws.NewOfflineInstanceTypeProvider([]byte(`{"version": "v1", "instanceTypes": [{"zones": ["us-west-2a"]}]}`))
+			Expect(err).Should(MatchError("offline catalog entry 0 has no instance type"))
+		})
+	})
+
+	Describe("Checking Health", func() {
+		It("should be healthy when instance types are discovered", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+			Expect(cloudprovideraws.NewInstanceTypeProvider(ec2api).Healthy(context.Background())).To(Succeed())
+		})
+		It("should be unhealthy when the EC2 API fails", func() {
+			ec2api := &fake.EC2API{EC2Behavior: fake.EC2Behavior{WantErr: fmt.Errorf("unauthorized")}}
+			Expect(cloudprovideraws.NewInstanceTypeProvider(ec2api).Healthy(context.Background())).ToNot(Succeed())
+		})
+		It("should be unhealthy when no instance types are discovered", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{})
+			Expect(cloudprovideraws.NewInstanceTypeProvider(ec2api).Healthy(context.Background())).ToNot(Succeed())
+		})
+	})
+
+	Describe("Validating Permissions", func() {
+		unauthorized := awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+
+		It("should succeed when both APIs are permitted", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			Expect(cloudprovideraws.NewInstanceTypeProvider(ec2api).Validate(context.Background())).To(Succeed())
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+		})
+		It("should name the missing permission to describe instance types", func() {
+			ec2api := &fake.EC2API{EC2Behavior: fake.EC2Behavior{WantErr: unauthorized}}
+			err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Validate(context.Background())
+			Expect(cloudprovideraws.IsPermissionError(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("ec2:DescribeInstanceTypes,"))
+		})
+		It("should name the missing permission to describe instance type offerings", func() {
+			ec2api := &fake.EC2API{EC2Behavior: fake.EC2Behavior{WantDescribeInstanceTypeOfferingsErr: unauthorized}}
+			err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Validate(context.Background())
+			Expect(cloudprovideraws.IsPermissionError(err)).Should(BeTrue())
+			Expect(err.Error()).Should(ContainSubstring("ec2:DescribeInstanceTypeOfferings"))
+		})
+		It("should not report throttling as a missing permission", func() {
+			ec2api := &fake.EC2API{EC2Behavior: fake.EC2Behavior{WantErr: awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil)}}
+			err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Validate(context.Background())
+			Expect(err).Should(HaveOccurred())
+			Expect(cloudprovideraws.IsPermissionError(err)).Should(BeFalse())
+			Expect(err.Error()).Should(ContainSubstring("throttled"))
+		})
+		It("should not report transient errors as a missing permission", func() {
+			ec2api := &fake.EC2API{EC2Behavior: fake.EC2Behavior{WantErr: fmt.Errorf("connection reset by peer")}}
+			err := cloudprovideraws.NewInstanceTypeProvider(ec2api).Validate(context.Background())
+			Expect(err).Should(HaveOccurred())
+			Expect(cloudprovideraws.IsPermissionError(err)).Should(BeFalse())
+		})
+	})
+
+	Describe("Discovering Instance Types Concurrently", func() {
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+		It("should describe instance types once for concurrent calls while the cache is cold", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			ec2api.DescribeDelay = 50 * time.Millisecond
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			var wg sync.WaitGroup
+			errs := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+					Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+		})
+		It("should stop the first caller waiting, but finish the shared discovery, if its context is cancelled", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			ec2api.DescribeDelay = 200 * time.Millisecond
+			ec2api.ThrottledDescribeCalls = 1
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).
+				WithDescribeBackoff(cloudprovideraws.Backoff{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond})
+			ctx, cancel := context.WithCancel(context.Background())
+			cancelled := make(chan error, 1)
+			go func() {
+				_, err := instanceTypeProvider.Get(ctx, zonalSubnetOptions, cloudprovideraws.Constraints{})
+				cancelled <- err
+			}()
+			time.Sleep(50 * time.Millisecond)
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+			}()
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cancelled).Should(Receive(MatchError(context.Canceled)))
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(2))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+		})
+	})
+
+	Describe("Recording Metrics", func() {
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+		It("should record discovery, cache lookups and EC2 call latency", func() {
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "t3.large"}))
+			misses := metricValue("karpenter_instance_types_cache_misses_total", "cache", "offerings")
+			hits := metricValue("karpenter_instance_types_cache_hits_total", "cache", "instance_types")
+			calls := metricValue("karpenter_instance_types_ec2_call_duration_seconds", "api", "DescribeInstanceTypes")
+
+			_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metricValue("karpenter_instance_types_discovered", "filters", "all")).Should(Equal(2.0))
+			Expect(metricValue("karpenter_instance_types_cache_misses_total", "cache", "offerings")).Should(Equal(misses + 1))
+			Expect(metricValue("karpenter_instance_types_ec2_call_duration_seconds", "api", "DescribeInstanceTypes")).Should(Equal(calls + 1))
+
+			_, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metricValue("karpenter_instance_types_cache_hits_total", "cache", "instance_types")).Should(Equal(hits + 1))
+			Expect(metricValue("karpenter_instance_types_ec2_call_duration_seconds", "api", "DescribeInstanceTypes")).Should(Equal(calls + 1))
+		})
+		It("should record the instance types discovered with each kind of filters separately", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			output := ec2api.(*fake.EC2API).DescribeInstanceTypesOutput
+			output.InstanceTypes = output.InstanceTypes[:1]
+			_, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints(cloudprovider.Constraints{CurrentGenerationOnly: true}))
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = instanceTypeProvider.Get(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{InstanceTypeFilters: map[string][]string{"hypervisor": {"nitro"}}}))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metricValue("karpenter_instance_types_discovered", "filters", "all")).Should(Equal(2.0))
+			Expect(metricValue("karpenter_instance_types_discovered", "filters", "current-generation")).Should(Equal(1.0))
+			Expect(metricValue("karpenter_instance_types_discovered", "filters", "custom")).Should(Equal(1.0))
+		})
+	})
+
+	Describe("Retrying Throttled Describe Calls", func() {
+		backoff := cloudprovideraws.Backoff{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+		zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+		It("should succeed after throttled calls are retried", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			ec2api.ThrottledDescribeCalls = 2
+			instanceTypes, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithDescribeBackoff(backoff).
+				Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceTypeNames(instanceTypes)).Should(ConsistOf("m5.large"))
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(3))
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(1))
+		})
+		It("should retry throttled offerings calls", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithDescribeBackoff(backoff).
+				WithCacheTTLs(time.Hour, time.Nanosecond)
+			Expect(instanceTypeProvider.Warm(context.Background())).To(Succeed())
+			ec2api.ThrottledDescribeCalls = 1
+			_, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(ec2api.CalledWithDescribeInstanceTypeOfferingsInput).Should(HaveLen(3))
+		})
+		It("should fail once the attempts are exhausted", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			ec2api.ThrottledDescribeCalls = 3
+			_, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithDescribeBackoff(backoff).
+				Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).Should(HaveOccurred())
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(3))
+		})
+		It("should not retry authorization errors", func() {
+			ec2api := &fake.EC2API{EC2Behavior: fake.EC2Behavior{
+				WantErr: awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil),
+			}}
+			_, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithDescribeBackoff(backoff).
+				Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).Should(HaveOccurred())
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+		})
+		It("should stop retrying when the context is done", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"}).(*fake.EC2API)
+			ec2api.ThrottledDescribeCalls = 1
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := cloudprovideraws.NewInstanceTypeProvider(ec2api).WithDescribeBackoff(cloudprovideraws.Backoff{Attempts: 3, Delay: time.Hour, MaxDelay: time.Hour}).
+				Get(ctx, zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).Should(MatchError(context.DeadlineExceeded))
+			Expect(ec2api.CalledWithDescribeInstanceTypesInput).Should(HaveLen(1))
+		})
+	})
+
+	Describe("Discovering Spot Availability", func() {
+		It("should record the zones each instance type is offered as spot in", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{"test-zone-1a", "test-zone-1b"}, []string{"m5.large", "t3.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api).
+				WithSpotAvailabilitySignal(fakeSpotAvailabilitySignal{"t3.large": {"test-zone-1a"}})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{"test-zone-1a": nil, "test-zone-1b": nil}
+			instanceTypes, err := instanceTypeProvider.Get(context.Background(), zonalSubnetOptions, cloudprovideraws.Constraints{})
+			Expect(err).ShouldNot(HaveOccurred())
+			zonalCapacityTypes := map[string]map[string][]string{}
+			for _, instanceType := range instanceTypes {
+				zonalCapacityTypes[*instanceType.InstanceType] = instanceType.ZonalCapacityTypes
+			}
+			Expect(zonalCapacityTypes).Should(Equal(map[string]map[string][]string{
+				"m5.large": nil,
+				"t3.large": {"test-zone-1a": {"on-demand", "spot"}, "test-zone-1b": {"on-demand"}},
+			}))
+		})
+	})
+
+	Describe("Getting a Selection Result", func() {
+		Context("With arm64 architecture and a mix of instance types", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large", "m6g.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+			constraints.Architecture = &v1alpha1.ArchitectureArm64
+			result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+
+			It("should report the matched instance types and eliminations", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Considered).Should(Equal(2))
+				Expect(len(result.Instances)).Should(Equal(1))
+				Expect(*result.Instances[0].InstanceType).Should(Equal("m6g.large"))
+				Expect(result.Eliminated).Should(Equal(map[string]int{"architecture": 1}))
+				Expect(result.Warnings).Should(BeEmpty())
+			})
+		})
+
+		Context("With constraints that no instance type satisfies", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{testZone}, []string{"m5.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+			constraints.Architecture = &v1alpha1.ArchitectureArm64
+			result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+
+			It("should warn that the selection is over-constrained", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Instances).Should(BeEmpty())
+				Expect(result.Eliminated).Should(Equal(map[string]int{"architecture": 1}))
+				Expect(result.Warnings).Should(HaveLen(1))
+			})
+		})
+
+		Context("With a summary", func() {
+			ec2api := getInstanceTypeProviderMocks([]string{"us-east-1b", "us-east-1a"}, []string{"m6g.large"})
+			instanceTypeProvider := cloudprovideraws.NewInstanceTypeProvider(ec2api)
+			zonalSubnetOptions := map[string][]*ec2.Subnet{"us-east-1a": nil, "us-east-1b": nil}
+			constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{Pods: []*v1.Pod{
+				test.PendingPodWith(test.PodOptions{ResourceRequirements: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				}}),
+			}})
+			constraints.Architecture = &v1alpha1.ArchitectureArm64
+			result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+
+			It("should describe the first instance type in one line", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Summary()).Should(Equal("Selected m6g.large (arm64, on-demand, 2 vCPU / 8 GiB) in us-east-1a,1b; 25% packing efficiency"))
+			})
+		})
+
+		Context("With GPU instance types and node taints", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: *instanceTypeMocks["m5.large"], Zones: []string{testZone}},
+				{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String("p3.2xlarge"),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+					GpuInfo: &ec2.GpuInfo{Gpus: []*ec2.GpuDeviceInfo{{
+						Manufacturer: aws.String("NVIDIA"),
+						Count:        aws.Int64(1),
+					}}},
+				}, Zones: []string{testZone}},
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+
+			It("should warn if GPU instance types are selected without the GPU taint", func() {
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions,
+					cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Warnings).Should(ConsistOf(ContainSubstring("without a nvidia.com/gpu taint")))
+			})
+			It("should warn if the GPU taint is intended without GPU instance types", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.InstanceTypes = []string{"m5.large"}
+				constraints.Taints = []v1.Taint{{Key: "nvidia.com/gpu", Effect: v1.TaintEffectNoSchedule}}
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Warnings).Should(ConsistOf(ContainSubstring("no instance types with nvidia.com/gpu")))
+			})
+			It("should not warn if the GPU taint is intended for GPU instance types", func() {
+				constraints := cloudprovideraws.Constraints(cloudprovider.Constraints{})
+				constraints.Taints = []v1.Taint{{Key: "nvidia.com/gpu", Effect: v1.TaintEffectNoSchedule}}
+				result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions, constraints)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Warnings).Should(BeEmpty())
+			})
+		})
+
+		Context("With none of the default instance families offered", func() {
+			instanceTypeProvider := cloudprovideraws.NewStaticInstanceTypeProvider([]*packing.Instance{
+				{InstanceTypeInfo: ec2.InstanceTypeInfo{
+					InstanceType:          aws.String("x1.16xlarge"),
+					SupportedUsageClasses: []*string{aws.String("on-demand")},
+					BareMetal:             aws.Bool(false),
+					ProcessorInfo:         &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"x86_64"})},
+				}, Zones: []string{testZone}},
+			})
+			zonalSubnetOptions := map[string][]*ec2.Subnet{testZone: nil}
+			result, err := instanceTypeProvider.GetResult(context.Background(), zonalSubnetOptions,
+				cloudprovideraws.Constraints(cloudprovider.Constraints{}))
+
+			It("should warn that the default instance families aren't offered", func() {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(result.Instances).Should(BeEmpty())
+				Expect(result.Eliminated).Should(Equal(map[string]int{"instanceType": 1}))
+				Expect(result.Warnings).Should(HaveLen(2))
+				Expect(result.Warnings[0]).Should(ContainSubstring("default instance families"))
+			})
+		})
+	})
+
+})
+
+// Test Helpers
+
+func getInstanceTypeProviderMocks(zones []string, instanceTypes []string) ec2iface.EC2API {
+	ec2api := &fake.EC2API{
+		EC2Behavior: fake.EC2Behavior{
+			DescribeInstanceTypesOutput:         &ec2.DescribeInstanceTypesOutput{},
+			DescribeInstanceTypeOfferingsOutput: &ec2.DescribeInstanceTypeOfferingsOutput{},
+		},
+	}
+
+	for _, instanceType := range instanceTypes {
+		ec2api.DescribeInstanceTypesOutput.InstanceTypes = append(ec2api.DescribeInstanceTypesOutput.InstanceTypes, instanceTypeMocks[instanceType])
+	}
+	for _, zone := range zones {
+		for _, instanceType := range instanceTypes {
+			offering := &ec2.InstanceTypeOffering{
+				InstanceType: aws.String(instanceType),
+				Location:     aws.String(zone),
+			}
+			ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings = append(ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings, offering)
+		}
+	}
+	return ec2api
+}
+
+func getInstanceTypeProviderMocksWithOfferings(offerings map[string][]string) ec2iface.EC2API {
+	ec2api := &fake.EC2API{
+		EC2Behavior: fake.EC2Behavior{
+			DescribeInstanceTypesOutput:         &ec2.DescribeInstanceTypesOutput{},
+			DescribeInstanceTypeOfferingsOutput: &ec2.DescribeInstanceTypeOfferingsOutput{},
+		},
+	}
+	for instanceType, zones := range offerings {
+		ec2api.DescribeInstanceTypesOutput.InstanceTypes = append(ec2api.DescribeInstanceTypesOutput.InstanceTypes, instanceTypeMocks[instanceType])
+		for _, zone := range zones {
+			ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings = append(ec2api.DescribeInstanceTypeOfferingsOutput.InstanceTypeOfferings, &ec2.InstanceTypeOffering{
+				InstanceType: aws.String(instanceType),
+				Location:     aws.String(zone),
+			})
+		}
+	}
+	return ec2api
+}
+
+func instanceTypeNames(instanceTypes []*packing.Instance) []string {
+	names := []string{}
+	for _, instanceType := range instanceTypes {
+		names = append(names, *instanceType.InstanceType)
+	}
+	return names
+}
+
+// expectSelected expects the instance types to be those named, or Get to have failed with a
+// NoMatchingInstanceTypesError if none are named
+func expectSelected(instanceTypes []*packing.Instance, err error, expected ...string) {
+	if len(expected) == 0 {
+		ExpectWithOffset(1, err).Should(BeAssignableToTypeOf(&cloudprovideraws.NoMatchingInstanceTypesError{}))
+	} else {
+		ExpectWithOffset(1, err).ShouldNot(HaveOccurred())
+	}
+	ExpectWithOffset(1, instanceTypeNames(instanceTypes)).Should(ConsistOf(expected))
+}
+
+// metricValue scrapes the registry for the value of the metric with the labels, given as name and value pairs, or
+// the number of observations if it's a histogram, which is zero if the metric hasn't been recorded
+func metricValue(name string, labels ...string) float64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).ShouldNot(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.Metric {
+			if !hasLabels(metric, labels...) {
+				continue
+			}
+			switch {
+			case metric.Counter != nil:
+				return metric.Counter.GetValue()
+			case metric.Gauge != nil:
+				return metric.Gauge.GetValue()
+			case metric.Histogram != nil:
+				return float64(metric.Histogram.GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+// hasLabels returns true if the metric has every label, given as name and value pairs
+func hasLabels(metric *dto.Metric, labels ...string) bool {
+	for i := 0; i+1 < len(labels); i += 2 {
+		if !hasLabel(metric, labels[i], labels[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLabel(metric *dto.Metric, name string, value string) bool {
+	for _, label := range metric.Label {
+		if label.GetName() == name && label.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeCapacitySignal returns launch failure rates keyed by instance type and zone, e.g. m5.large/test-zone
+type fakeCapacitySignal map[string]float64
+
+func (f fakeCapacitySignal) LaunchFailureRate(instanceType string, zone string) float64 {
+	return f[instanceType+"/"+zone]
+}
+
+// fakeLaunchLatencySignal returns launch latencies keyed by instance type
+type fakeLaunchLatencySignal map[string]time.Duration
+
+func (f fakeLaunchLatencySignal) LaunchLatency(instanceType string) time.Duration {
+	return f[instanceType]
+}
+
+// fakePriceSource returns prices keyed by capacity type and then instance type, or an error if the
+// capacity type has no prices
+type fakePriceSource map[string]map[string]float64
+
+func (f fakePriceSource) Prices(_ context.Context, _ string, capacityType string) (map[string]float64, error) {
+	prices, ok := f[capacityType]
+	if !ok {
+		return nil, fmt.Errorf("no %s prices", capacityType)
+	}
+	return prices, nil
+}
+
+// countingPriceSource counts the calls for each capacity type's prices, which it retrieves after the delay
+type countingPriceSource struct {
+	prices fakePriceSource
+	delay  time.Duration
+	mu     sync.Mutex
+	calls  map[string]int
+}
+
+func (c *countingPriceSource) Prices(ctx context.Context, region string, capacityType string) (map[string]float64, error) {
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = map[string]int{}
+	}
+	c.calls[capacityType]++
+	c.mu.Unlock()
+	time.Sleep(c.delay)
+	return c.prices.Prices(ctx, region, capacityType)
+}
+
+type fakeSpotAvailabilitySignal map[string][]string
+
+func (f fakeSpotAvailabilitySignal) SpotZones(instanceType string) []string {
+	return f[instanceType]
+}
+
+// fakeInterruptionSource returns interruption frequency ratings keyed by instance type, or an error if nil
+type fakeInterruptionSource map[string]string
+
+func (f fakeInterruptionSource) InterruptionFrequencies(_ context.Context, _ string) (map[string]string, error) {
+	if f == nil {
+		return nil, fmt.Errorf("no interruption frequencies")
+	}
+	return f, nil
 }
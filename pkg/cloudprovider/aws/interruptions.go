@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/awslabs/karpenter/pkg/packing"
+	"go.uber.org/zap"
+)
+
+const (
+	interruptionsKeyPrefix = "interruptions/"
+	// SpotAdvisorURL serves the data behind the Spot Instance Advisor, including
+	// the interruption frequency of each instance type in each region
+	SpotAdvisorURL = "https://spot-bid-advisor.s3.amazonaws.com/spot-advisor-data.json"
+	// neutralInterruptionScore is the score of spot instance types whose
+	// interruption frequency is unknown, which neither favors nor penalizes them
+	neutralInterruptionScore = 0.5
+)
+
+// interruptionScores maps the Spot Instance Advisor's interruption frequency
+// ratings to scores from 0 for the least to 1 for the most frequently interrupted
+var interruptionScores = map[string]float64{
+	"<5%":    0,
+	"5-10%":  0.25,
+	"10-15%": 0.5,
+	"15-20%": 0.75,
+	">20%":   1,
+}
+
+// InterruptionSource reports how frequently spot instance types are
+// interrupted, e.g. from the Spot Instance Advisor. Ratings are cached, so
+// implementations may call remote APIs.
+type InterruptionSource interface {
+	// InterruptionFrequencies returns the interruption frequency rating of
+	// each instance type in the region, e.g. "<5%" or "5-10%", keyed by
+	// instance type
+	InterruptionFrequencies(ctx context.Context, region string) (map[string]string, error)
+}
+
+// WithInterruptionSource scores spot instance types by the source's interruption frequency ratings, which the
+// packer uses to break ties between equally sized and priced instance types. If the ratings can't be retrieved,
+// instance types are scored as neutral. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithInterruptionSource(source InterruptionSource) *InstanceTypeProvider {
+	p.interruptionSource = source
+	return p
+}
+
+// InterruptionScoreOf returns the score of the interruption frequency rating, or the neutral score if the
+// rating isn't recognized
+func InterruptionScoreOf(rating string) float64 {
+	if score, ok := interruptionScores[rating]; ok {
+		return score
+	}
+	return neutralInterruptionScore
+}
+
+// withInterruptionScores scores each instance type whose most preferred capacity type is spot, if there is an
+// interruption source. Instance types that aren't rated, or all of them if the ratings can't be retrieved,
+// are scored as neutral.
+func (p *InstanceTypeProvider) withInterruptionScores(ctx context.Context, instanceTypes []*packing.Instance) {
+	if p.interruptionSource == nil {
+		return
+	}
+	ratings, err := p.getInterruptionFrequencies(ctx)
+	if err != nil {
+		zap.S().Debugf("Continuing with neutral interruption scores, %s", err.Error())
+	}
+	for _, instanceType := range instanceTypes {
+		if len(instanceType.CapacityTypes) == 0 || instanceType.CapacityTypes[0] != capacityTypeSpot {
+			continue
+		}
+		instanceType.InterruptionScore = InterruptionScoreOf(ratings[*instanceType.InstanceType])
+	}
+}
+
+// getInterruptionFrequencies returns the cached interruption frequency ratings, retrieving them if the cache is cold
+func (p *InstanceTypeProvider) getInterruptionFrequencies(ctx context.Context) (map[string]string, error) {
+	if ratings, ok := p.cache.Get(interruptionsKeyPrefix + p.region); ok {
+		return ratings.(map[string]string), nil
+	}
+	ratings, err := p.interruptionSource.InterruptionFrequencies(ctx, p.region)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving interruption frequencies, %w", err)
+	}
+	p.cache.Set(interruptionsKeyPrefix+p.region, ratings, PricingCacheTTL)
+	return ratings, nil
+}
+
+// AWSInterruptionSource retrieves the interruption frequency ratings of Linux instance types from the data
+// behind the Spot Instance Advisor
+type AWSInterruptionSource struct {
+	client *http.Client
+	url    string
+}
+
+// NewAWSInterruptionSource returns an interruption source that retrieves ratings from the url, e.g. SpotAdvisorURL
+func NewAWSInterruptionSource(client *http.Client, url string) *AWSInterruptionSource {
+	return &AWSInterruptionSource{client: client, url: url}
+}
+
+// spotAdvisorData is the subset of the Spot Instance Advisor's data that rates interruption frequencies. Each
+// instance type's rating is the index of a range, keyed by region, operating system and then instance type.
+type spotAdvisorData struct {
+	Ranges []struct {
+		Index int    `json:"index"`
+		Label string `json:"label"`
+	} `json:"ranges"`
+	SpotAdvisor map[string]map[string]map[string]struct {
+		Rating int `json:"r"`
+	} `json:"spot_advisor"`
+}
+
+func (s *AWSInterruptionSource) InterruptionFrequencies(ctx context.Context, region string) (map[string]string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s, %w", s.url, err)
+	}
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s, %w", s.url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting %s, %s", s.url, response.Status)
+	}
+	data := spotAdvisorData{}
+	if err := json.NewDecoder(response.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decoding spot advisor data, %w", err)
+	}
+	labels := map[int]string{}
+	for _, r := range data.Ranges {
+		labels[r.Index] = r.Label
+	}
+	ratings := map[string]string{}
+	for instanceType, advice := range data.SpotAdvisor[region]["Linux"] {
+		if label, ok := labels[advice.Rating]; ok {
+			ratings[instanceType] = label
+		}
+	}
+	return ratings, nil
+}
@@ -57,17 +57,31 @@ type LaunchTemplateProvider struct {
 	clientSet               *kubernetes.Clientset
 }
 
-func launchTemplateName(clusterName string, arch string) string {
-	return fmt.Sprintf(launchTemplateNameFormat, clusterName, arch)
+// launchTemplateName returns the name of the launch template for the architecture and tenancy, which is only
+// suffixed for tenancies other than default so that existing launch templates are still used
+func launchTemplateName(clusterName string, arch string, tenancy string) string {
+	name := fmt.Sprintf(launchTemplateNameFormat, clusterName, arch)
+	if tenancy != "" && tenancy != ec2.TenancyDefault {
+		name += "-" + tenancy
+	}
+	return name
+}
+
+// placementFor returns the placement of instances launched with the tenancy, or nil for the default tenancy
+func placementFor(tenancy string) *ec2.LaunchTemplatePlacementRequest {
+	if tenancy == "" || tenancy == ec2.TenancyDefault {
+		return nil
+	}
+	return &ec2.LaunchTemplatePlacementRequest{Tenancy: aws.String(tenancy)}
 }
 
 func (p *LaunchTemplateProvider) Get(ctx context.Context, cluster *v1alpha1.ClusterSpec, constraints Constraints) (*ec2.LaunchTemplate, error) {
 	arch := utils.NormalizeArchitecture(constraints.Architecture)
-	name := launchTemplateName(cluster.Name, *arch)
+	name := launchTemplateName(cluster.Name, *arch, constraints.Tenancy)
 	if launchTemplate, ok := p.cache.Get(name); ok {
 		return launchTemplate.(*ec2.LaunchTemplate), nil
 	}
-	launchTemplate, err := p.getLaunchTemplate(ctx, cluster, *arch)
+	launchTemplate, err := p.getLaunchTemplate(ctx, cluster, *arch, constraints.Tenancy)
 	if err != nil {
 		return nil, err
 	}
@@ -76,12 +90,12 @@ func (p *LaunchTemplateProvider) Get(ctx context.Context, cluster *v1alpha1.Clus
 }
 
 // TODO, reconcile launch template if not equal to desired launch template (AMI upgrade, role changed, etc)
-func (p *LaunchTemplateProvider) getLaunchTemplate(ctx context.Context, cluster *v1alpha1.ClusterSpec, arch string) (*ec2.LaunchTemplate, error) {
+func (p *LaunchTemplateProvider) getLaunchTemplate(ctx context.Context, cluster *v1alpha1.ClusterSpec, arch string, tenancy string) (*ec2.LaunchTemplate, error) {
 	describelaunchTemplateOutput, err := p.ec2api.DescribeLaunchTemplatesWithContext(ctx, &ec2.DescribeLaunchTemplatesInput{
-		LaunchTemplateNames: []*string{aws.String(launchTemplateName(cluster.Name, arch))},
+		LaunchTemplateNames: []*string{aws.String(launchTemplateName(cluster.Name, arch, tenancy))},
 	})
 	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidLaunchTemplateName.NotFoundException" {
-		return p.createLaunchTemplate(ctx, cluster, arch)
+		return p.createLaunchTemplate(ctx, cluster, arch, tenancy)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("describing launch templates, %w", err)
@@ -94,7 +108,7 @@ func (p *LaunchTemplateProvider) getLaunchTemplate(ctx context.Context, cluster
 	return launchTemplate, nil
 }
 
-func (p *LaunchTemplateProvider) createLaunchTemplate(ctx context.Context, cluster *v1alpha1.ClusterSpec, arch string) (*ec2.LaunchTemplate, error) {
+func (p *LaunchTemplateProvider) createLaunchTemplate(ctx context.Context, cluster *v1alpha1.ClusterSpec, arch string, tenancy string) (*ec2.LaunchTemplate, error) {
 	securityGroupIds, err := p.getSecurityGroupIds(ctx, cluster)
 	if err != nil {
 		return nil, fmt.Errorf("getting security groups, %w", err)
@@ -114,7 +128,7 @@ func (p *LaunchTemplateProvider) createLaunchTemplate(ctx context.Context, clust
 	}
 
 	output, err := p.ec2api.CreateLaunchTemplate(&ec2.CreateLaunchTemplateInput{
-		LaunchTemplateName: aws.String(launchTemplateName(cluster.Name, arch)),
+		LaunchTemplateName: aws.String(launchTemplateName(cluster.Name, arch, tenancy)),
 		LaunchTemplateData: &ec2.RequestLaunchTemplateData{
 			IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
 				Name: instanceProfile.InstanceProfileName,
@@ -135,6 +149,7 @@ func (p *LaunchTemplateProvider) createLaunchTemplate(ctx context.Context, clust
 			SecurityGroupIds: securityGroupIds,
 			UserData:         userData,
 			ImageId:          amiID,
+			Placement:        placementFor(tenancy),
 		},
 	})
 	if err != nil {
@@ -0,0 +1,36 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Launch Template Tenancy", func() {
+	It("should keep the name and placement of the default tenancy", func() {
+		for _, tenancy := range []string{"", ec2.TenancyDefault} {
+			Expect(launchTemplateName("test-cluster", "amd64", tenancy)).Should(Equal("Karpenter-test-cluster-amd64"))
+			Expect(placementFor(tenancy)).Should(BeNil())
+		}
+	})
+	It("should name and place launch templates for other tenancies", func() {
+		Expect(launchTemplateName("test-cluster", "arm64", ec2.TenancyDedicated)).Should(Equal("Karpenter-test-cluster-arm64-dedicated"))
+		Expect(placementFor(ec2.TenancyDedicated)).Should(Equal(&ec2.LaunchTemplatePlacementRequest{Tenancy: aws.String(ec2.TenancyDedicated)}))
+		Expect(placementFor(ec2.TenancyHost)).Should(Equal(&ec2.LaunchTemplatePlacementRequest{Tenancy: aws.String(ec2.TenancyHost)}))
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	instanceTypesCache    = "instance_types"
+	instanceTypeInfoCache = "instance_type_info"
+	offeringsCache        = "offerings"
+	selectionsCache       = "selections"
+)
+
+var (
+	discoveredInstanceTypes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "karpenter",
+			Subsystem: "instance_types",
+			Name:      "discovered",
+			Help:      "Number of instance types offered in any zone, as of the most recent discovery of the region's instance types with the filters, i.e. all, current-generation, or custom for any custom filters.",
+		},
+		[]string{"region", "filters"},
+	)
+	instanceTypeCacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "karpenter",
+			Subsystem: "instance_types",
+			Name:      "cache_hits_total",
+			Help:      "Number of lookups of instance types, instance type info, offerings or selections that were served from the cache.",
+		},
+		[]string{"cache"},
+	)
+	instanceTypeCacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "karpenter",
+			Subsystem: "instance_types",
+			Name:      "cache_misses_total",
+			Help:      "Number of lookups of instance types, instance type info, offerings or selections that weren't cached.",
+		},
+		[]string{"cache"},
+	)
+	ec2CallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "karpenter",
+			Subsystem: "instance_types",
+			Name:      "ec2_call_duration_seconds",
+			Help:      "Duration of each attempt of the paginated EC2 describe calls that discover instance types, including every page.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+		},
+		[]string{"api"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(discoveredInstanceTypes, instanceTypeCacheHits, instanceTypeCacheMisses, ec2CallDuration)
+}
+
+// filtersLabelFor returns the filters label of instance types discovered for the cache key, which is the key
+// unless there are custom filters, whose keys are hashed and so would add a series for each set of filters
+func filtersLabelFor(key string, customFilters []*ec2.Filter) string {
+	if len(customFilters) > 0 {
+		return "custom"
+	}
+	return key
+}
+
+// recordCacheLookup counts a lookup of the cache as a hit or a miss
+func recordCacheLookup(cache string, hit bool) {
+	if hit {
+		instanceTypeCacheHits.WithLabelValues(cache).Inc()
+	} else {
+		instanceTypeCacheMisses.WithLabelValues(cache).Inc()
+	}
+}
+
+// timed records the duration of the EC2 call in the latency histogram of the api
+func timed(api string, call func() error) error {
+	start := time.Now()
+	defer func() { ec2CallDuration.WithLabelValues(api).Observe(time.Since(start).Seconds()) }()
+	return call()
+}
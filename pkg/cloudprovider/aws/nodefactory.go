@@ -21,6 +21,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -57,9 +58,14 @@ func (n *NodeFactory) nodesFrom(reservations []*ec2.Reservation) map[string]*v1.
 }
 
 func (n *NodeFactory) nodeFrom(instance *ec2.Instance) *v1.Node {
+	labels := map[string]string{}
+	if instance.InstanceType != nil {
+		labels[v1alpha1.InstanceTypeLabelKey] = *instance.InstanceType
+	}
 	return &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: *instance.PrivateDnsName,
+			Name:   *instance.PrivateDnsName,
+			Labels: labels,
 		},
 		Spec: v1.NodeSpec{
 			ProviderID: fmt.Sprintf("aws:///%s/%s", *instance.Placement.AvailabilityZone, *instance.InstanceId),
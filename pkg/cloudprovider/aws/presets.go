@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/awslabs/karpenter/pkg/packing"
+)
+
+// preset is the expansion of a workload profile, see cloudprovider.Constraints.Preset
+type preset struct {
+	// familyPrefixes replace the default family prefixes, and only match
+	// families of the same category, e.g. d matches d3en but not dl1
+	familyPrefixes []string
+	// minMemoryMiBPerVCPU and maxMemoryMiBPerVCPU bound the ratio of memory
+	// to vCPUs. Zero means unbounded.
+	minMemoryMiBPerVCPU int64
+	maxMemoryMiBPerVCPU int64
+	requireNvidiaGPU    bool
+	requireAccelerator  bool
+	requireLocalStorage bool
+}
+
+var presets = map[string]preset{
+	"general": {
+		familyPrefixes:      []string{"m", "t3", "t4"},
+		minMemoryMiBPerVCPU: 2048,
+		maxMemoryMiBPerVCPU: 4096,
+	},
+	"compute": {
+		familyPrefixes:      []string{"c"},
+		maxMemoryMiBPerVCPU: 2048,
+	},
+	"memory": {
+		familyPrefixes:      []string{"r", "x", "z"},
+		minMemoryMiBPerVCPU: 8192,
+	},
+	"gpu-training": {
+		familyPrefixes:   []string{"p"},
+		requireNvidiaGPU: true,
+	},
+	"gpu-inference": {
+		familyPrefixes:     []string{"g", "inf"},
+		requireAccelerator: true,
+	},
+	"storage": {
+		familyPrefixes:      []string{"i", "im", "is", "d", "h"},
+		requireLocalStorage: true,
+	},
+}
+
+// defaultsFor returns the constraints with the preset's requirements for the fields they leave unset, so that
+// explicit constraints override the preset. The memory to vCPU ratio is only defaulted if neither bound is set.
+func (p preset) defaultsFor(constraints Constraints) Constraints {
+	if constraints.MinMemoryMiBPerVCPU == 0 && constraints.MaxMemoryMiBPerVCPU == 0 {
+		constraints.MinMemoryMiBPerVCPU = p.minMemoryMiBPerVCPU
+		constraints.MaxMemoryMiBPerVCPU = p.maxMemoryMiBPerVCPU
+	}
+	if !constraints.RequireLocalStorage && constraints.MinLocalStorageGB == 0 {
+		constraints.RequireLocalStorage = p.requireLocalStorage
+	}
+	return constraints
+}
+
+// matchesAccelerators is true if the instance type has the accelerators the preset requires, unless the
+// constraints explicitly constrain accelerators, e.g. by the pods' requests, in which case they apply instead
+func (p preset) matchesAccelerators(instance *packing.Instance, constraints Constraints) bool {
+	if constrainsAccelerators(constraints) {
+		return true
+	}
+	if p.requireNvidiaGPU && packing.CountNvidiaGPUs(instance) == 0 {
+		return false
+	}
+	if p.requireAccelerator {
+		if count, _ := acceleratorsOf(instance); count == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// constrainsAccelerators is true if the constraints restrict instance types by their accelerators
+func constrainsAccelerators(constraints Constraints) bool {
+	for _, extended := range defaultExtendedResources {
+		if _, requested := largestRequestFor(constraints.Pods, extended.resource); requested {
+			return true
+		}
+	}
+	return constraints.GPUPodsPerNode != 0 || constraints.MinVCPUsPerGPU != 0 || constraints.MaxVCPUsPerGPU != 0 ||
+		constraints.MinGPUMemoryMiB != 0 || constraints.RequireFPGA || constraints.MinFPGAs != 0
+}
@@ -0,0 +1,251 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/awslabs/karpenter/pkg/packing"
+	"go.uber.org/zap"
+)
+
+const pricesKeyPrefix = "prices/"
+
+// PriceSource reports the hourly prices of instance types, e.g. from the AWS
+// Pricing API. Prices are cached, so implementations may call remote APIs.
+type PriceSource interface {
+	// Prices returns the hourly price in USD of each instance type in the
+	// region for the capacity type, keyed by instance type
+	Prices(ctx context.Context, region string, capacityType string) (map[string]float64, error)
+}
+
+// WithPriceSource sets the price of selected instance types from the source, which the packer uses to break
+// ties between equally sized instance types. If prices can't be retrieved, instance types are selected without
+// them. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithPriceSource(source PriceSource) *InstanceTypeProvider {
+	p.priceSource = source
+	return p
+}
+
+// withPrices sets the price of each instance type's most preferred capacity type, if there is a price source.
+// Instance types whose capacity type's prices can't be retrieved are left unpriced.
+func (p *InstanceTypeProvider) withPrices(ctx context.Context, instanceTypes []*packing.Instance) {
+	if p.priceSource == nil {
+		return
+	}
+	unpriced := map[string]bool{}
+	for _, instanceType := range instanceTypes {
+		if len(instanceType.CapacityTypes) == 0 || unpriced[instanceType.CapacityTypes[0]] {
+			continue
+		}
+		prices, err := p.getPrices(ctx, instanceType.CapacityTypes[0])
+		if err != nil {
+			zap.S().Debugf("Continuing without %s prices, %s", instanceType.CapacityTypes[0], err.Error())
+			unpriced[instanceType.CapacityTypes[0]] = true
+			continue
+		}
+		instanceType.Price = prices[*instanceType.InstanceType]
+	}
+}
+
+// Cheapest returns the name and hourly price of the cheapest instance type that satisfies the constraints, priced
+// for its most preferred capacity type, e.g. to estimate costs without packing pods. Instance types are selected
+// as by GetResult. Instance types without a price
+// aren't considered. It returns an error if there's no price source or no priced instance type is selected.
+func (p *InstanceTypeProvider) Cheapest(ctx context.Context, zonalSubnetOptions map[string][]*ec2.Subnet, constraints Constraints) (string, float64, error) {
+	if p.priceSource == nil {
+		return "", 0, fmt.Errorf("pricing instance types without a price source")
+	}
+	result, err := p.getResult(ctx, zonalSubnetOptions, constraints, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	instanceTypes := result.Instances
+	if len(instanceTypes) == 0 {
+		return "", 0, fmt.Errorf("no instance types satisfy the constraints")
+	}
+	var cheapest *packing.Instance
+	for _, instanceType := range instanceTypes {
+		if instanceType.Price > 0 && (cheapest == nil || instanceType.Price < cheapest.Price) {
+			cheapest = instanceType
+		}
+	}
+	if cheapest == nil {
+		return "", 0, fmt.Errorf("none of the %d instance types that satisfy the constraints are priced", len(instanceTypes))
+	}
+	return *cheapest.InstanceType, cheapest.Price, nil
+}
+
+// getPrices returns the cached prices of the capacity type, retrieving them once for concurrent callers if the
+// cache is cold. Failures are cached for PricingErrorCacheTTL, so that selections don't each wait for a price
+// source that's failing.
+func (p *InstanceTypeProvider) getPrices(ctx context.Context, capacityType string) (map[string]float64, error) {
+	key := pricesKeyPrefix + capacityType
+	if cached, ok := p.cache.Get(key); ok {
+		if err, failed := cached.(error); failed {
+			return nil, err
+		}
+		return cached.(map[string]float64), nil
+	}
+	prices, err := p.pricings.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		prices, err := p.priceSource.Prices(ctx, p.region, capacityType)
+		if err != nil {
+			err = fmt.Errorf("retrieving %s prices, %w", capacityType, err)
+			if ctx.Err() == nil {
+				p.cache.Set(key, err, PricingErrorCacheTTL)
+			}
+			return nil, err
+		}
+		p.cache.Set(key, prices, PricingCacheTTL)
+		return prices, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prices.(map[string]float64), nil
+}
+
+// AWSPriceSource retrieves on-demand prices from the AWS Pricing API and spot prices from EC2's spot price history
+type AWSPriceSource struct {
+	pricingapi pricingiface.PricingAPI
+	ec2api     ec2iface.EC2API
+}
+
+// NewAWSPriceSource returns a price source for Linux instance types with shared tenancy. The Pricing API is
+// only served from some regions, e.g. us-east-1, regardless of the region that prices are retrieved for.
+func NewAWSPriceSource(pricingapi pricingiface.PricingAPI, ec2api ec2iface.EC2API) *AWSPriceSource {
+	return &AWSPriceSource{pricingapi: pricingapi, ec2api: ec2api}
+}
+
+func (s *AWSPriceSource) Prices(ctx context.Context, region string, capacityType string) (map[string]float64, error) {
+	if capacityType == capacityTypeSpot {
+		return s.spotPrices(ctx)
+	}
+	return s.onDemandPrices(ctx, region)
+}
+
+// onDemandPrices returns the on-demand prices from the price list's OnDemand terms
+func (s *AWSPriceSource) onDemandPrices(ctx context.Context, region string) (map[string]float64, error) {
+	filters := []*pricing.Filter{}
+	for field, value := range map[string]string{
+		"regionCode":      region,
+		"operatingSystem": "Linux",
+		"tenancy":         "Shared",
+		"preInstalledSw":  "NA",
+		"capacitystatus":  "Used",
+	} {
+		filters = append(filters, &pricing.Filter{Field: aws.String(field), Type: aws.String(pricing.FilterTypeTermMatch), Value: aws.String(value)})
+	}
+	prices := map[string]float64{}
+	var parseErr error
+	err := s.pricingapi.GetProductsPagesWithContext(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters:     filters,
+	}, func(output *pricing.GetProductsOutput, lastPage bool) bool {
+		for _, product := range output.PriceList {
+			instanceType, price, err := onDemandPriceOf(product)
+			if err != nil {
+				parseErr = err
+				return false
+			}
+			if instanceType != "" {
+				prices[instanceType] = price
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting products from the pricing API, %w", err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("parsing the price list, %w", parseErr)
+	}
+	return prices, nil
+}
+
+// onDemandPriceOf returns the instance type and hourly price of a price list product, or an empty
+// instance type if the product isn't priced hourly in USD
+func onDemandPriceOf(product aws.JSONValue) (string, float64, error) {
+	attributes, _ := lookup(map[string]interface{}(product), "product", "attributes").(map[string]interface{})
+	instanceType, _ := attributes["instanceType"].(string)
+	terms, _ := lookup(map[string]interface{}(product), "terms", "OnDemand").(map[string]interface{})
+	for _, term := range terms {
+		dimensions, _ := lookup(term, "priceDimensions").(map[string]interface{})
+		for _, dimension := range dimensions {
+			if unit, _ := lookup(dimension, "unit").(string); unit != "Hrs" {
+				continue
+			}
+			usd, ok := lookup(dimension, "pricePerUnit", "USD").(string)
+			if !ok {
+				continue
+			}
+			price, err := strconv.ParseFloat(usd, 64)
+			if err != nil {
+				return "", 0, fmt.Errorf("parsing price %q of %s, %w", usd, instanceType, err)
+			}
+			return instanceType, price, nil
+		}
+	}
+	return "", 0, nil
+}
+
+// lookup returns the value at the path of keys in nested JSON objects, or nil if there isn't one
+func lookup(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// spotPrices returns the lowest current spot price of each instance type across zones
+func (s *AWSPriceSource) spotPrices(ctx context.Context) (map[string]float64, error) {
+	prices := map[string]float64{}
+	var parseErr error
+	err := s.ec2api.DescribeSpotPriceHistoryPagesWithContext(ctx, &ec2.DescribeSpotPriceHistoryInput{
+		ProductDescriptions: aws.StringSlice([]string{"Linux/UNIX"}),
+		StartTime:           aws.Time(time.Now()),
+	}, func(output *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
+		for _, spotPrice := range output.SpotPriceHistory {
+			price, err := strconv.ParseFloat(aws.StringValue(spotPrice.SpotPrice), 64)
+			if err != nil {
+				parseErr = fmt.Errorf("parsing spot price of %s, %w", aws.StringValue(spotPrice.InstanceType), err)
+				return false
+			}
+			if current, ok := prices[aws.StringValue(spotPrice.InstanceType)]; !ok || price < current {
+				prices[aws.StringValue(spotPrice.InstanceType)] = price
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing spot price history, %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return prices, nil
+}
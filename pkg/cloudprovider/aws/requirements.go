@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
+	"github.com/awslabs/karpenter/pkg/packing"
+	"github.com/awslabs/karpenter/pkg/utils/functional"
+)
+
+const (
+	// Values of InstanceRequirements.BareMetal and BurstablePerformance
+	RequirementIncluded = "included"
+	RequirementExcluded = "excluded"
+	RequirementRequired = "required"
+)
+
+// InstanceRequirements mirrors the shape of EC2 attribute-based instance type
+// selection, so that specs written for EC2 Fleet or Auto Scaling groups can
+// be reused. Unset fields are unconstrained. Names and values match EC2's,
+// e.g. CpuManufacturers of "intel", "amd" or "amazon-web-services".
+// https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InstanceRequirements.html
+type InstanceRequirements struct {
+	VCpuCount                *Int64Range
+	MemoryMiB                *Int64Range
+	AcceleratorCount         *Int64Range
+	AcceleratorManufacturers []*string
+	BareMetal                *string
+	BurstablePerformance     *string
+	CpuManufacturers         []*string
+	ExcludedInstanceTypes    []*string
+	InstanceGenerations      []*string
+}
+
+// Int64Range is an inclusive range. A nil bound is unbounded.
+type Int64Range struct {
+	Min *int64
+	Max *int64
+}
+
+func (r *Int64Range) contains(value int64) bool {
+	return r == nil || ((r.Min == nil || value >= *r.Min) && (r.Max == nil || value <= *r.Max))
+}
+
+// predicates returns the requirements as predicates, named after the field they check, or none without requirements
+func (r *InstanceRequirements) predicates() []predicate {
+	if r == nil {
+		return nil
+	}
+	return []predicate{
+		{name: "requirements.excludedInstanceTypes", matches: func(instance *packing.Instance) bool {
+			for _, pattern := range aws.StringValueSlice(r.ExcludedInstanceTypes) {
+				if excluded, _ := path.Match(pattern, *instance.InstanceType); excluded {
+					return false
+				}
+			}
+			return true
+		}},
+		{name: "requirements.instanceGenerations", matches: func(instance *packing.Instance) bool {
+			generation := "previous"
+			if aws.BoolValue(instance.CurrentGeneration) {
+				generation = "current"
+			}
+			return len(r.InstanceGenerations) == 0 || containsFold(aws.StringValueSlice(r.InstanceGenerations), generation)
+		}},
+		{name: "requirements.cpuManufacturers", matches: func(instance *packing.Instance) bool {
+			return len(r.CpuManufacturers) == 0 || containsFold(aws.StringValueSlice(r.CpuManufacturers), cpuManufacturerOf(instance))
+		}},
+		{name: "requirements.vCpuCount", matches: func(instance *packing.Instance) bool {
+			return r.VCpuCount == nil || (instance.VCpuInfo != nil && r.VCpuCount.contains(aws.Int64Value(instance.VCpuInfo.DefaultVCpus)))
+		}},
+		{name: "requirements.memoryMiB", matches: func(instance *packing.Instance) bool {
+			return r.MemoryMiB == nil || (instance.MemoryInfo != nil && r.MemoryMiB.contains(aws.Int64Value(instance.MemoryInfo.SizeInMiB)))
+		}},
+		{name: "requirements.bareMetal", matches: func(instance *packing.Instance) bool {
+			return isIncluded(r.BareMetal, aws.BoolValue(instance.BareMetal))
+		}},
+		{name: "requirements.burstablePerformance", matches: func(instance *packing.Instance) bool {
+			return isIncluded(r.BurstablePerformance, aws.BoolValue(instance.BurstablePerformanceSupported))
+		}},
+		{name: "requirements.acceleratorCount", matches: func(instance *packing.Instance) bool {
+			count, _ := acceleratorsOf(instance)
+			return r.AcceleratorCount.contains(count)
+		}},
+		{name: "requirements.acceleratorManufacturers", matches: func(instance *packing.Instance) bool {
+			if len(r.AcceleratorManufacturers) == 0 {
+				return true
+			}
+			_, manufacturers := acceleratorsOf(instance)
+			for _, manufacturer := range manufacturers {
+				if containsFold(aws.StringValueSlice(r.AcceleratorManufacturers), manufacturer) {
+					return true
+				}
+			}
+			return false
+		}},
+	}
+}
+
+// isIncluded returns true if an instance type with or without the attribute satisfies the requirement
+func isIncluded(requirement *string, attribute bool) bool {
+	switch aws.StringValue(requirement) {
+	case RequirementExcluded:
+		return !attribute
+	case RequirementRequired:
+		return attribute
+	default:
+		return true
+	}
+}
+
+// cpuManufacturerOf infers the processor manufacturer from the architecture, since every arm64 instance type runs
+// on Graviton even if its family name doesn't say so, e.g. a1, and otherwise from the family name
+func cpuManufacturerOf(instance *packing.Instance) string {
+	if functional.ContainsString(supportedArchitecturesOf(instance), v1alpha1.ArchitectureArm64) {
+		return "amazon-web-services"
+	}
+	switch parseFamily(familyOf(*instance.InstanceType)).processor {
+	case "a":
+		return "amd"
+	case "g":
+		return "amazon-web-services"
+	default:
+		return "intel"
+	}
+}
+
+// acceleratorsOf returns the total number of GPUs, FPGAs and inference
+// accelerators attached to the instance type, and their manufacturers
+func acceleratorsOf(instance *packing.Instance) (int64, []string) {
+	count := int64(0)
+	manufacturers := []string{}
+	if instance.GpuInfo != nil {
+		for _, gpu := range instance.GpuInfo.Gpus {
+			count += aws.Int64Value(gpu.Count)
+			manufacturers = append(manufacturers, aws.StringValue(gpu.Manufacturer))
+		}
+	}
+	if instance.FpgaInfo != nil {
+		for _, fpga := range instance.FpgaInfo.Fpgas {
+			count += aws.Int64Value(fpga.Count)
+			manufacturers = append(manufacturers, aws.StringValue(fpga.Manufacturer))
+		}
+	}
+	if instance.InferenceAcceleratorInfo != nil {
+		for _, accelerator := range instance.InferenceAcceleratorInfo.Accelerators {
+			count += aws.Int64Value(accelerator.Count)
+			manufacturers = append(manufacturers, aws.StringValue(accelerator.Manufacturer))
+		}
+	}
+	return count, manufacturers
+}
+
+// containsFold returns true if the values contain the value, ignoring case.
+// EC2 reports manufacturers as e.g. NVIDIA and AWS, while requirements use nvidia
+// and amazon-web-services.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) || (strings.EqualFold(v, "amazon-web-services") && strings.EqualFold(value, "AWS")) {
+			return true
+		}
+	}
+	return false
+}
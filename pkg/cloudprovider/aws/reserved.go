@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"math"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/karpenter/pkg/packing"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const mebibyte = 1024 * 1024
+
+// Reserved is CPU and memory that every node reserves for system daemons or the
+// kubelet, e.g. with --system-reserved or --kube-reserved, so pods can't use it
+type Reserved struct {
+	CPU    resource.Quantity
+	Memory resource.Quantity
+	// MemoryPercent of each instance type's memory is reserved in addition to
+	// Memory, from 0 to 100
+	MemoryPercent float64
+}
+
+// memoryFor returns the bytes of memory reserved on an instance type with the given memory, rounded up
+func (r Reserved) memoryFor(memoryMiB int64) int64 {
+	return r.Memory.Value() + int64(math.Ceil(float64(memoryMiB*mebibyte)*r.MemoryPercent/100))
+}
+
+// isZero is true if nothing is reserved
+func (r Reserved) isZero() bool {
+	return r.CPU.IsZero() && r.Memory.IsZero() && r.MemoryPercent == 0
+}
+
+// WithReserved reserves the system and kube reserved CPU and memory on the instance types returned
+// by Allocatable. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithReserved(systemReserved Reserved, kubeReserved Reserved) *InstanceTypeProvider {
+	p.systemReserved = systemReserved
+	p.kubeReserved = kubeReserved
+	return p
+}
+
+// Allocatable returns copies of the instance types that reserve the system and kube reserved CPU and
+// memory, so that pods aren't packed onto nodes that can't run them. The reservations replace the
+// kubelet overhead the packer otherwise estimates. Instance types are returned as they are if
+// nothing is reserved.
+func (p *InstanceTypeProvider) Allocatable(instanceTypes []*packing.Instance) []*packing.Instance {
+	if p.systemReserved.isZero() && p.kubeReserved.isZero() {
+		return instanceTypes
+	}
+	reservedCPU := p.systemReserved.CPU.MilliValue() + p.kubeReserved.CPU.MilliValue()
+	allocatable := []*packing.Instance{}
+	for _, instanceType := range instanceTypes {
+		copied := *instanceType
+		memoryMiB := int64(0)
+		if instanceType.MemoryInfo != nil {
+			memoryMiB = aws.Int64Value(instanceType.MemoryInfo.SizeInMiB)
+		}
+		copied.Reserved = v1.ResourceList{
+			v1.ResourceCPU:    *resource.NewMilliQuantity(reservedCPU, resource.DecimalSI),
+			v1.ResourceMemory: *resource.NewQuantity(p.systemReserved.memoryFor(memoryMiB)+p.kubeReserved.memoryFor(memoryMiB), resource.BinarySI),
+		}
+		allocatable = append(allocatable, &copied)
+	}
+	return allocatable
+}
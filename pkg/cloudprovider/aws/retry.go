@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"go.uber.org/zap"
+)
+
+// DefaultDescribeBackoff retries describe calls that are throttled or fail
+// with server errors after the SDK's own retries are exhausted
+var DefaultDescribeBackoff = Backoff{Attempts: 3, Delay: 1 * time.Second, MaxDelay: 10 * time.Second}
+
+// Backoff bounds the retries of paginated EC2 describe calls
+type Backoff struct {
+	// Attempts is the maximum number of calls, including the first
+	Attempts int
+	// Delay is the time before the first retry, which doubles after each
+	// retry up to MaxDelay
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// WithDescribeBackoff overrides how paginated EC2 describe calls are retried when they're throttled or
+// fail with server errors. It returns the same provider simply for ease of use.
+func (p *InstanceTypeProvider) WithDescribeBackoff(backoff Backoff) *InstanceTypeProvider {
+	p.describeBackoff = backoff
+	return p
+}
+
+// retry calls describe until it succeeds, fails with an error that isn't retryable, the attempts are
+// exhausted, or the context is done. Paginated calls are retried from the first page, so describe must
+// discard the pages of failed attempts.
+func (b Backoff) retry(ctx context.Context, name string, describe func() error) error {
+	delay := b.Delay
+	for attempt := 1; ; attempt++ {
+		err := describe()
+		if err == nil || !isRetryable(err) || attempt >= b.Attempts {
+			return err
+		}
+		zap.S().Debugf("Retrying %s after attempt %d of %d, %s", name, attempt, b.Attempts, err.Error())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay + time.Duration(rand.Int63n(int64(delay)/10+1))):
+		}
+		if delay *= 2; delay > b.MaxDelay {
+			delay = b.MaxDelay
+		}
+	}
+}
+
+// isRetryable is true for throttling, server and transient errors, but not for authorization or other client errors
+func isRetryable(err error) bool {
+	if request.IsErrorThrottle(err) || request.IsErrorRetryable(err) {
+		return true
+	}
+	if aerr, ok := err.(awserr.RequestFailure); ok {
+		return aerr.StatusCode() >= 500
+	}
+	return false
+}
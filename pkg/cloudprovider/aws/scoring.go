@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"math"
+
+	"github.com/awslabs/karpenter/pkg/packing"
+)
+
+// InstanceTypeScorer scores the selected instance types to order them by,
+// e.g. to prefer cheaper, faster or more stable instance types, separately
+// from the constraints that select them.
+type InstanceTypeScorer interface {
+	// Score is higher for instance types that should be preferred. Prices
+	// and interruption scores are set before instance types are scored.
+	Score(instance *packing.Instance) float64
+}
+
+// InstanceTypeScorerFunc adapts a function to an InstanceTypeScorer
+type InstanceTypeScorerFunc func(instance *packing.Instance) float64
+
+func (f InstanceTypeScorerFunc) Score(instance *packing.Instance) float64 {
+	return f(instance)
+}
+
+// DefaultScorer scores every instance type equally, keeping the order they're selected in
+var DefaultScorer InstanceTypeScorer = InstanceTypeScorerFunc(func(*packing.Instance) float64 {
+	return 0
+})
+
+// CheapestFirstScorer prefers instance types with lower prices. Instance types without a price, e.g. without a
+// price source, are scored last, keeping the order they're selected in.
+var CheapestFirstScorer InstanceTypeScorer = InstanceTypeScorerFunc(func(instance *packing.Instance) float64 {
+	if instance.Price == 0 {
+		return math.Inf(-1)
+	}
+	return -instance.Price
+})
+
+// WithScorer ranks selected instance types by the scorer, highest first, which the packer keeps ahead of size.
+// Instance types that score equally keep the order they're selected in. It returns the same provider simply for
+// ease of use.
+func (p *InstanceTypeProvider) WithScorer(scorer InstanceTypeScorer) *InstanceTypeProvider {
+	p.scorer = scorer
+	return p
+}
+
+// scored ranks the instance types by the provider's scorer, if any, highest first
+func (p *InstanceTypeProvider) scored(instanceTypes []*packing.Instance) []*packing.Instance {
+	if p.scorer == nil {
+		return instanceTypes
+	}
+	return rankBy(instanceTypes, func(instance *packing.Instance) float64 {
+		return -p.scorer.Score(instance)
+	})
+}
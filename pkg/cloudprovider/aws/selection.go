@@ -0,0 +1,535 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
+	"github.com/awslabs/karpenter/pkg/cloudprovider"
+	"github.com/awslabs/karpenter/pkg/cloudprovider/aws/utils"
+	"github.com/awslabs/karpenter/pkg/packing"
+	"github.com/awslabs/karpenter/pkg/utils/functional"
+	"github.com/awslabs/karpenter/pkg/utils/resources"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	v1 "k8s.io/api/core/v1"
+)
+
+// SelectionResult describes the outcome of filtering instance types against constraints
+type SelectionResult struct {
+	// Instances that satisfied every predicate
+	Instances []*packing.Instance
+	// Considered is the number of candidate instance types that were evaluated
+	Considered int
+	// Eliminated counts the candidates rejected by each predicate, keyed by
+	// predicate name. Candidates are attributed to the first predicate they fail.
+	Eliminated map[string]int
+	// Warnings are non-fatal observations about the selection
+	Warnings []string
+	// CapacityType and Zones are those the instance types were selected for
+	CapacityType string
+	Zones        []string
+	// Efficiency is the fraction of the first instance type that the pods and
+	// overhead would use, or zero if there are no pods
+	Efficiency float64
+}
+
+// Summary describes the first selected instance type in one line, e.g. Selected
+// c6g.2xlarge (arm64, spot, 8 vCPU / 16 GiB) in us-east-1a,1b,1c; 40% packing efficiency
+func (r *SelectionResult) Summary() string {
+	if len(r.Instances) == 0 {
+		return fmt.Sprintf("Selected no instance types, all %d were eliminated", r.Considered)
+	}
+	instance := r.Instances[0]
+	zones := []string{}
+	for _, zone := range instance.Zones {
+		if len(r.Zones) == 0 || functional.ContainsString(r.Zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Strings(zones)
+	summary := fmt.Sprintf("Selected %s (%s, %s, %d vCPU / %g GiB) in %s",
+		aws.StringValue(instance.InstanceType),
+		strings.Join(supportedArchitecturesOf(instance), "/"),
+		r.CapacityType,
+		packing.VCPUsOf(instance),
+		float64(packing.MemoryMiBOf(instance))/1024,
+		abbreviateZones(zones),
+	)
+	if r.Efficiency > 0 {
+		summary += fmt.Sprintf("; %.0f%% packing efficiency", r.Efficiency*100)
+	}
+	return summary
+}
+
+// copy returns a copy of the result whose instance types, eliminations and warnings can be modified independently
+func (r *SelectionResult) copy() *SelectionResult {
+	copied := *r
+	copied.Instances = make([]*packing.Instance, 0, len(r.Instances))
+	for _, instance := range r.Instances {
+		instanceCopy := *instance
+		copied.Instances = append(copied.Instances, &instanceCopy)
+	}
+	copied.Eliminated = make(map[string]int, len(r.Eliminated))
+	for name, count := range r.Eliminated {
+		copied.Eliminated[name] = count
+	}
+	copied.Warnings = append([]string{}, r.Warnings...)
+	return &copied
+}
+
+// abbreviateZones joins zones, omitting the region from all but the first, e.g. us-east-1a,1b
+func abbreviateZones(zones []string) string {
+	abbreviated := []string{}
+	for i, zone := range zones {
+		if i > 0 {
+			if region := zones[0][:strings.LastIndex(zones[0], "-")+1]; strings.HasPrefix(zone, region) {
+				zone = strings.TrimPrefix(zone, region)
+			}
+		}
+		abbreviated = append(abbreviated, zone)
+	}
+	return strings.Join(abbreviated, ",")
+}
+
+// CapacityPool is an instance type in a zone, which EC2 allocates spot capacity from independently
+type CapacityPool struct {
+	InstanceType string
+	Zone         string
+}
+
+// predicate is a named check that an instance type must satisfy to be selected
+type predicate struct {
+	name    string
+	matches func(*packing.Instance) bool
+}
+
+// firstFailing returns the first predicate the instance type does not satisfy, or nil if all are satisfied
+func firstFailing(predicates []predicate, instanceType *packing.Instance) *predicate {
+	for i := range predicates {
+		if !predicates[i].matches(instanceType) {
+			return &predicates[i]
+		}
+	}
+	return nil
+}
+
+// logStages logs, at debug level, how many candidates entered and survived each predicate in order, as if each
+// filtered the survivors of the last, e.g. to find which of several constraints is too strict
+func logStages(considered int, predicates []predicate, eliminated map[string]int) {
+	if !zap.L().Core().Enabled(zapcore.DebugLevel) {
+		return
+	}
+	remaining := considered
+	for _, name := range append([]string{"malformed"}, namesOf(predicates)...) {
+		surviving := remaining - eliminated[name]
+		zap.S().Debugw("Filtered instance types", "predicate", name, "entering", remaining, "surviving", surviving)
+		remaining = surviving
+	}
+}
+
+func namesOf(predicates []predicate) []string {
+	names := []string{}
+	for i := range predicates {
+		names = append(names, predicates[i].name)
+	}
+	return names
+}
+
+// wellFormed returns the instance types that have a name, logging and skipping the rest rather than letting
+// selection dereference them. DescribeInstanceTypes occasionally returns incomplete info, so predicates treat
+// the other fields as optional.
+func wellFormed(instanceTypes []*packing.Instance) []*packing.Instance {
+	named := []*packing.Instance{}
+	for _, instanceType := range instanceTypes {
+		if instanceType.InstanceType == nil {
+			zap.S().Debugf("Skipping instance type with incomplete info, missing InstanceType")
+			continue
+		}
+		named = append(named, instanceType)
+	}
+	return named
+}
+
+// supportedArchitecturesOf returns the architectures the instance type supports, e.g. x86_64 or arm64, or none if
+// its processor info is missing
+func supportedArchitecturesOf(instanceType *packing.Instance) []string {
+	if instanceType.ProcessorInfo == nil {
+		return nil
+	}
+	return aws.StringValueSlice(instanceType.ProcessorInfo.SupportedArchitectures)
+}
+
+// allFailing returns the names of every predicate the instance type does not satisfy, in order
+func allFailing(predicates []predicate, instanceType *packing.Instance) []string {
+	failing := []string{}
+	for i := range predicates {
+		if !predicates[i].matches(instanceType) {
+			failing = append(failing, predicates[i].name)
+		}
+	}
+	return failing
+}
+
+// orderBySize orders instance types by vCPUs, then memory, then name, so that selection is
+// deterministic regardless of the order instance types were discovered in
+func orderBySize(instanceTypes []*packing.Instance) []*packing.Instance {
+	sort.SliceStable(instanceTypes, func(i, j int) bool {
+		vCPUsI, vCPUsJ := packing.VCPUsOf(instanceTypes[i]), packing.VCPUsOf(instanceTypes[j])
+		if vCPUsI != vCPUsJ {
+			return vCPUsI < vCPUsJ
+		}
+		memoryI, memoryJ := packing.MemoryMiBOf(instanceTypes[i]), packing.MemoryMiBOf(instanceTypes[j])
+		if memoryI != memoryJ {
+			return memoryI < memoryJ
+		}
+		return *instanceTypes[i].InstanceType < *instanceTypes[j].InstanceType
+	})
+	return instanceTypes
+}
+
+// preferFamilies ranks instance types in the given families first, otherwise preserving order.
+// If none of the instance types are in the given families, the order is unchanged.
+func preferFamilies(instanceTypes []*packing.Instance, families []string) []*packing.Instance {
+	if len(families) == 0 {
+		return instanceTypes
+	}
+	return rankBy(instanceTypes, func(instance *packing.Instance) float64 {
+		return unless(functional.ContainsString(families, familyOf(*instance.InstanceType)))
+	})
+}
+
+// preferBurstable ranks burstable instance types first, otherwise preserving order
+func preferBurstable(instanceTypes []*packing.Instance) []*packing.Instance {
+	return rankBy(instanceTypes, func(instance *packing.Instance) float64 {
+		return unless(isBurstable(instance))
+	})
+}
+
+// isBurstable returns true if the instance type accrues CPU credits, as reported by EC2, or else if it's in
+// the t category, e.g. t3 and t4g but not trn1
+func isBurstable(instance *packing.Instance) bool {
+	if instance.BurstablePerformanceSupported != nil {
+		return *instance.BurstablePerformanceSupported
+	}
+	return parseFamily(familyOf(*instance.InstanceType)).category == "t"
+}
+
+// appliesTo returns true if the commitments discount the capacity type
+func appliesTo(commitments *cloudprovider.CommitmentCoverage, capacityType string) bool {
+	if len(commitments.CapacityTypes) == 0 {
+		return capacityType == capacityTypeOnDemand
+	}
+	return functional.ContainsString(commitments.CapacityTypes, capacityType)
+}
+
+// covers returns true if the commitments discount the instance type
+func covers(commitments *cloudprovider.CommitmentCoverage, instance *packing.Instance) bool {
+	return functional.ContainsString(commitments.InstanceTypes, *instance.InstanceType) ||
+		functional.ContainsString(commitments.InstanceFamilies, familyOf(*instance.InstanceType))
+}
+
+// preferCovered ranks instance types covered by the commitments first, otherwise preserving order
+func preferCovered(instanceTypes []*packing.Instance, commitments *cloudprovider.CommitmentCoverage, capacityType string) []*packing.Instance {
+	if commitments == nil || !appliesTo(commitments, capacityType) {
+		return instanceTypes
+	}
+	return rankBy(instanceTypes, func(instance *packing.Instance) float64 {
+		return unless(covers(commitments, instance))
+	})
+}
+
+// fitOf returns the fraction of the instance type's size along the dimension that the requests would
+// use, or false if the requests don't fit. Higher values indicate a more efficient fit.
+func fitOf(instance *packing.Instance, requests v1.ResourceList, dimension v1.ResourceName) (float64, bool) {
+	if instance.VCpuInfo == nil || instance.VCpuInfo.DefaultVCpus == nil ||
+		instance.MemoryInfo == nil || instance.MemoryInfo.SizeInMiB == nil ||
+		*instance.VCpuInfo.DefaultVCpus == 0 || *instance.MemoryInfo.SizeInMiB == 0 {
+		return 0, false
+	}
+	cpu := float64(requests.Cpu().MilliValue()) / float64(*instance.VCpuInfo.DefaultVCpus*1000)
+	memory := float64(requests.Memory().Value()) / float64(*instance.MemoryInfo.SizeInMiB*1024*1024)
+	if cpu > 1 || memory > 1 {
+		return 0, false
+	}
+	switch dimension {
+	case v1.ResourceCPU:
+		return cpu, true
+	case v1.ResourceMemory:
+		return memory, true
+	default:
+		return (cpu + memory) / 2, true
+	}
+}
+
+// rankByFit returns the instance types that fit all of the constraints' pods and overhead on a single
+// node, ranked from the most to the least efficient fit
+func rankByFit(instanceTypes []*packing.Instance, constraints Constraints) []*packing.Instance {
+	requests := resources.Merge(resources.RequestsForPods(constraints.Pods...), constraints.Overhead)
+	fits := map[*packing.Instance]float64{}
+	candidates := []*packing.Instance{}
+	for _, instanceType := range instanceTypes {
+		if fit, ok := fitOf(instanceType, requests, constraints.SizeDimension); ok {
+			fits[instanceType] = fit
+			candidates = append(candidates, instanceType)
+		}
+	}
+	return rankBy(candidates, func(instance *packing.Instance) float64 { return -fits[instance] })
+}
+
+// orderByFit ranks the instance types that fit all of the constraints' pods and overhead on a single
+// node first, from the most to the least efficient fit, otherwise preserving order
+func orderByFit(instanceTypes []*packing.Instance, constraints Constraints) []*packing.Instance {
+	requests := resources.Merge(resources.RequestsForPods(constraints.Pods...), constraints.Overhead)
+	return rankBy(instanceTypes, func(instance *packing.Instance) float64 {
+		if fit, ok := fitOf(instance, requests, constraints.SizeDimension); ok {
+			return -fit
+		}
+		return 1
+	})
+}
+
+// rankBy orders the instance types by the key, lowest first, and then by their existing rank, otherwise
+// preserving order. Their Rank is set to match, so that the packer keeps the instance types the key prefers
+// first, regardless of size. Instance types with equal keys and ranks share a rank.
+func rankBy(instanceTypes []*packing.Instance, key func(*packing.Instance) float64) []*packing.Instance {
+	keys := make(map[*packing.Instance]float64, len(instanceTypes))
+	for _, instanceType := range instanceTypes {
+		keys[instanceType] = key(instanceType)
+	}
+	less := func(a, b *packing.Instance) bool {
+		if keys[a] != keys[b] {
+			return keys[a] < keys[b]
+		}
+		return a.Rank < b.Rank
+	}
+	sort.SliceStable(instanceTypes, func(i, j int) bool { return less(instanceTypes[i], instanceTypes[j]) })
+	rank := 0
+	for i, instanceType := range instanceTypes {
+		if i > 0 && less(instanceTypes[i-1], instanceType) {
+			rank++
+		}
+		instanceType.Rank = rank
+	}
+	return instanceTypes
+}
+
+// unless returns zero if the condition is true, so that instance types satisfying it are ranked first
+func unless(condition bool) float64 {
+	if condition {
+		return 0
+	}
+	return 1
+}
+
+func containsInstance(instanceTypes []*packing.Instance, instanceType *packing.Instance) bool {
+	for _, candidate := range instanceTypes {
+		if candidate == instanceType {
+			return true
+		}
+	}
+	return false
+}
+
+// acceleratorTaints are the taint keys conventionally applied to nodes with an
+// accelerator, so that only pods which tolerate them use the accelerator
+var acceleratorTaints = []struct {
+	key         string
+	accelerated func(*packing.Instance) bool
+}{
+	{key: resources.NvidiaGPU, accelerated: func(instance *packing.Instance) bool { return packing.CountNvidiaGPUs(instance) > 0 }},
+	{key: resources.AMDGPU, accelerated: func(instance *packing.Instance) bool { return packing.CountAMDGPUs(instance) > 0 }},
+	{key: resources.AWSNeuron, accelerated: func(instance *packing.Instance) bool { return instance.InferenceAcceleratorInfo != nil }},
+}
+
+// taintWarningsFor warns if accelerated instance types were selected without the accelerator's
+// conventional taint, or the taint is intended but no instance types with the accelerator were selected
+func taintWarningsFor(taints []v1.Taint, instanceTypes []*packing.Instance) []string {
+	warnings := []string{}
+	for _, acceleratorTaint := range acceleratorTaints {
+		tainted := false
+		for _, taint := range taints {
+			tainted = tainted || taint.Key == acceleratorTaint.key
+		}
+		accelerated := false
+		for _, instanceType := range instanceTypes {
+			accelerated = accelerated || acceleratorTaint.accelerated(instanceType)
+		}
+		if accelerated && !tainted {
+			warnings = append(warnings, fmt.Sprintf("instance types with %s were selected without a %s taint, so pods that don't need it may use them",
+				acceleratorTaint.key, acceleratorTaint.key))
+		}
+		if tainted && !accelerated && len(instanceTypes) > 0 {
+			warnings = append(warnings, fmt.Sprintf("nodes are tainted with %s, but no instance types with %s were selected",
+				acceleratorTaint.key, acceleratorTaint.key))
+		}
+	}
+	return warnings
+}
+
+// supportedCapacityTypes returns the capacity types the instance type supports, in order, that it's offered
+// as in any of its eligible zones. An empty capacity type is supported by every instance type.
+func supportedCapacityTypes(capacityTypes []string, zones []string, instance *packing.Instance) []string {
+	supported := []string{}
+	for _, capacityType := range capacityTypes {
+		if capacityType == "" || (functional.ContainsString(aws.StringValueSlice(instance.SupportedUsageClasses), capacityType) &&
+			isOfferedInAnyOf(zones, capacityType, instance)) {
+			supported = append(supported, capacityType)
+		}
+	}
+	return supported
+}
+
+// isOfferedInAnyOf is true if the instance type is offered as the capacity type in any of its zones that are
+// eligible, or any of its zones if unconstrained. Instance types without capacity types by zone are offered
+// as every capacity type they support in all of their zones.
+func isOfferedInAnyOf(zones []string, capacityType string, instance *packing.Instance) bool {
+	if instance.ZonalCapacityTypes == nil {
+		return true
+	}
+	for _, zone := range instance.Zones {
+		if (len(zones) == 0 || functional.ContainsString(zones, zone)) && instance.IsOfferedIn(zone, capacityType) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCapacityTypes returns copies of the instance types tagged with the capacity types they support, so
+// that the discovered instance types aren't modified
+func withCapacityTypes(instanceTypes []*packing.Instance, capacityTypes []string, zones []string) []*packing.Instance {
+	tagged := []*packing.Instance{}
+	for _, instanceType := range instanceTypes {
+		copied := *instanceType
+		copied.CapacityTypes = supportedCapacityTypes(capacityTypes, zones, instanceType)
+		tagged = append(tagged, &copied)
+	}
+	return tagged
+}
+
+// withoutZones copies the instance types without the excluded zones, so that they're neither selected nor
+// launched in, regardless of the subnets
+func withoutZones(instanceTypes []*packing.Instance, excluded []string) []*packing.Instance {
+	if len(excluded) == 0 {
+		return instanceTypes
+	}
+	remaining := []*packing.Instance{}
+	for _, instanceType := range instanceTypes {
+		copied := *instanceType
+		copied.Zones = []string{}
+		for _, zone := range instanceType.Zones {
+			if !functional.ContainsString(excluded, zone) {
+				copied.Zones = append(copied.Zones, zone)
+			}
+		}
+		remaining = append(remaining, &copied)
+	}
+	return remaining
+}
+
+// architecturesFor returns the acceptable architectures in order of preference, which are Architectures if
+// they're set, or else Architecture if it's set
+func architecturesFor(constraints Constraints) []string {
+	if len(constraints.Architectures) != 0 {
+		return constraints.Architectures
+	}
+	if constraints.Architecture != nil {
+		return []string{*constraints.Architecture}
+	}
+	return nil
+}
+
+// withArchitectures tags the instance types with the most preferred of the architectures they support, or
+// with amd64 or else arm64 if they don't support any of them, e.g. if explicit instance types weren't
+// restricted to an architecture
+func withArchitectures(instanceTypes []*packing.Instance, architectures []string) {
+	preferences := append(append([]string{}, architectures...), v1alpha1.ArchitectureAmd64, v1alpha1.ArchitectureArm64)
+	for _, instanceType := range instanceTypes {
+		instanceType.Architecture = ""
+		for _, architecture := range preferences {
+			if functional.ContainsString(supportedArchitecturesOf(instanceType), aws.StringValue(utils.NormalizeArchitecture(&architecture))) {
+				instanceType.Architecture = architecture
+				break
+			}
+		}
+	}
+}
+
+// cpuAndMemoryRequestsOf returns the cpu and memory requests of each of the pods
+func cpuAndMemoryRequestsOf(pods []*v1.Pod) []v1.ResourceList {
+	podRequests := []v1.ResourceList{}
+	for _, pod := range pods {
+		requests := resources.RequestsForPods(pod)
+		podRequests = append(podRequests, v1.ResourceList{v1.ResourceCPU: *requests.Cpu(), v1.ResourceMemory: *requests.Memory()})
+	}
+	return podRequests
+}
+
+// gpusPerPodFor returns the largest number of NVIDIA GPUs requested by any one of the pods
+func gpusPerPodFor(pods []*v1.Pod) int64 {
+	gpusPerPod, _ := largestRequestFor(pods, resources.NvidiaGPU)
+	return gpusPerPod
+}
+
+// largestRequestFor returns the largest quantity of the resource requested by any one of the pods, rounded up,
+// and whether any of them request it
+func largestRequestFor(pods []*v1.Pod, resourceName v1.ResourceName) (int64, bool) {
+	largest, requested := int64(0), false
+	for _, pod := range pods {
+		if quantity, ok := resources.RequestsForPods(pod)[resourceName]; ok {
+			requested = true
+			if quantity.Value() > largest {
+				largest = quantity.Value()
+			}
+		}
+	}
+	return largest, requested
+}
+
+// exhaustedZonesOf returns the zones, sorted, where every subnet reports fewer available IP addresses than the
+// minimum, or none. Subnets that don't report their available IP addresses are assumed to have enough.
+func exhaustedZonesOf(zonalSubnetOptions map[string][]*ec2.Subnet, minimum int64) []string {
+	if minimum < 1 {
+		minimum = 1
+	}
+	exhausted := []string{}
+	for zone, subnets := range zonalSubnetOptions {
+		if len(subnets) == 0 {
+			continue
+		}
+		available := false
+		for _, subnet := range subnets {
+			available = available || subnet.AvailableIpAddressCount == nil || *subnet.AvailableIpAddressCount >= minimum
+		}
+		if !available {
+			exhausted = append(exhausted, zone)
+		}
+	}
+	sort.Strings(exhausted)
+	return exhausted
+}
+
+func zonesFrom(zonalSubnetOptions map[string][]*ec2.Subnet) []string {
+	zones := []string{}
+	for zone := range zonalSubnetOptions {
+		zones = append(zones, zone)
+	}
+	return zones
+}
@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// singleFlight deduplicates concurrent calls with the same key, so that only one of them calls the
+// function while the rest wait for and share its result. The zero value is ready to use.
+type singleFlight struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done   chan struct{}
+	value  interface{}
+	err    error
+	cancel context.CancelFunc
+	// waiters is the number of callers whose contexts aren't done waiting for the result
+	waiters int
+}
+
+// do calls fn once for all concurrent calls with the key. The function is called in the background with a context
+// that keeps the first caller's values but isn't cancelled with it, so that callers, including the first, whose
+// contexts are done stop waiting and return the context's error without aborting the call that the others share.
+// The call is only cancelled once every caller's context is done.
+func (g *singleFlight) do(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	if f, ok := g.flights[key]; ok {
+		f.waiters++
+		g.mu.Unlock()
+		return g.wait(ctx, f)
+	}
+	flightCtx, cancel := context.WithCancel(detachedContext{ctx})
+	f := &flight{done: make(chan struct{}), cancel: cancel, waiters: 1}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	go func() {
+		f.value, f.err = fn(flightCtx)
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		cancel()
+		close(f.done)
+	}()
+	return g.wait(ctx, f)
+}
+
+// wait returns the result of the call, or the context's error if it's done first
+func (g *singleFlight) wait(ctx context.Context, f *flight) (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		g.leave(f)
+		return nil, ctx.Err()
+	}
+}
+
+// leave stops counting a caller that's no longer waiting, and cancels the call if no callers are
+func (g *singleFlight) leave(f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f.waiters--; f.waiters == 0 {
+		f.cancel()
+	}
+}
+
+// detachedContext keeps the values of its parent, e.g. the logger, but not its deadline or cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -16,6 +16,7 @@ package cloudprovider
 
 import (
 	"context"
+	"time"
 
 	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
 	v1 "k8s.io/api/core/v1"
@@ -53,8 +54,234 @@ type Constraints struct {
 	v1alpha1.Constraints
 	// Pods is a list of equivalently schedulable pods to be binpacked.
 	Pods []*v1.Pod
-	// Overhead resources per node from daemonsets.
+	// Overhead resources per node from daemonsets, including a pod for each
+	// daemonset. Instance types that it leaves too little room on for each of
+	// Pods are excluded.
 	Overhead v1.ResourceList
+	// CapacityTypes nodes may use, in order of preference, e.g. spot and then
+	// on-demand. If unspecified, the capacity type label is used, or else
+	// on-demand.
+	CapacityTypes []string
+	// AllowOnDemandFallback selects instance types for on-demand if none can
+	// be selected for CapacityTypes, e.g. if no instance types that satisfy
+	// the other constraints are offered as spot in the zones. It has no effect
+	// if CapacityTypes include on-demand.
+	AllowOnDemandFallback bool
+	// Architectures restricts nodes to instance types that support any of
+	// these architectures, in order of preference, e.g. amd64 and arm64 for
+	// multi-arch images. If set, it replaces Architecture, and each node is
+	// labeled with the architecture of its instance type.
+	Architectures []string
+	// RequireTrunkENI restricts nodes to instance types that support trunk
+	// network interfaces, which are required by security groups for pods.
+	RequireTrunkENI bool
+	// MaxHourlyCost caps the hourly cost in USD of the nodes created for Pods,
+	// estimated from the number of nodes they're packed onto and the price of
+	// the most expensive instance type each node may launch as. Pods that
+	// exceed it, or whose instance types aren't priced, aren't provisioned.
+	// Zero means unconstrained.
+	MaxHourlyCost float64
+	// MinEBSMaximumIOPS restricts nodes to instance types whose EBS optimized
+	// maximum IOPS is at least this value. Zero means unconstrained.
+	MinEBSMaximumIOPS int64
+	// RequireEBSOptimization restricts nodes to instance types that support EBS
+	// optimization, either by default or when enabled at launch.
+	RequireEBSOptimization bool
+	// MinEBSBaselineThroughputMBps restricts nodes to instance types whose EBS
+	// optimized baseline throughput is at least this value. Zero means
+	// unconstrained.
+	MinEBSBaselineThroughputMBps float64
+	// PreferredInstanceFamilies are families already in use by a workload's
+	// nodes. Matching instance types are ordered first without excluding others.
+	PreferredInstanceFamilies []string
+	// MinZones restricts nodes to instance types offered in at least this many
+	// of the eligible zones. Zero means unconstrained.
+	MinZones int
+	// ExcludedZones removes these zones from consideration even if there are
+	// subnets in them, e.g. to drain a zone that's impaired.
+	ExcludedZones []string
+	// MinSubnetAvailableIPs excludes zones where every subnet reports fewer
+	// available IP addresses, since nodes launched there couldn't host pods.
+	// Zones whose subnets report no available IP addresses are always
+	// excluded.
+	MinSubnetAvailableIPs int64
+	// Microarchitectures restricts nodes to instance types built on one of
+	// these processor microarchitectures, e.g. graviton3 or ice-lake.
+	Microarchitectures []string
+	// RequireEBSEncryption restricts nodes to instance types that support
+	// encrypted EBS volumes, e.g. for AMIs that require an encrypted boot volume.
+	RequireEBSEncryption bool
+	// SizeDimension is the resource that determines how large an instance
+	// type is when ranking by size, either cpu or memory. If unspecified, cpu
+	// and memory are weighed equally.
+	SizeDimension v1.ResourceName
+	// RequireConfigurableThreadsPerCore restricts nodes to instance types that
+	// support more than one threads per core value, so that simultaneous
+	// multithreading can be both enabled and disabled.
+	RequireConfigurableThreadsPerCore bool
+	// MinPhysicalCores restricts nodes to instance types with at least this
+	// many physical cores by default, regardless of how many vCPUs each core
+	// has, e.g. for software licensed per core. Zero means unconstrained.
+	MinPhysicalCores int64
+	// RequireInTransitEncryption restricts nodes to instance types that
+	// automatically encrypt traffic to other supported instances in the VPC.
+	RequireInTransitEncryption bool
+	// GPUPodsPerNode restricts nodes to instance types with enough NVIDIA GPUs
+	// for this many of the pods to share a node. Zero means unconstrained.
+	GPUPodsPerNode int
+	// MinMemoryMiB and MaxMemoryMiB restrict nodes to instance types whose
+	// memory is within the range, e.g. 8192 and 65536 to keep nodes between
+	// 8 and 64 GiB. Zero means unbounded.
+	MinMemoryMiB int64
+	MaxMemoryMiB int64
+	// MinMemoryMiBPerVCPU and MaxMemoryMiBPerVCPU restrict nodes to instance
+	// types whose ratio of memory to vCPUs is within the range, e.g. 2048 and
+	// 8192 for 1:2 to 1:8 GiB, without naming compute or memory optimized
+	// families. Zero means unconstrained.
+	MinMemoryMiBPerVCPU int64
+	MaxMemoryMiBPerVCPU int64
+	// MinVCPUsPerGPU and MaxVCPUsPerGPU restrict nodes to instance types with
+	// NVIDIA GPUs whose ratio of vCPUs to GPUs is within the range. Zero means
+	// unconstrained.
+	MinVCPUsPerGPU int64
+	MaxVCPUsPerGPU int64
+	// MinGPUMemoryMiB restricts nodes to instance types whose GPUs have at
+	// least this much memory in total. Zero means unconstrained.
+	MinGPUMemoryMiB int64
+	// MinElasticIPs restricts nodes to instance types that can associate at
+	// least this many elastic IPs, one per private IPv4 address across all
+	// network interfaces. Zero means unconstrained.
+	MinElasticIPs int64
+	// Preset names a workload profile that replaces the default instance
+	// families and defaults requirements. Explicit constraints override the
+	// preset: InstanceTypes replace its families, either memory per vCPU
+	// bound replaces its ratio, and accelerator constraints or requests
+	// replace its accelerator requirement.
+	//   general: m, t3 and t4 families with 2-4 GiB of memory per vCPU
+	//   compute: c families with at most 2 GiB of memory per vCPU
+	//   memory: r, x and z families with at least 8 GiB of memory per vCPU
+	//   gpu-training: p families with NVIDIA GPUs
+	//   gpu-inference: g and inf families with GPUs or inference accelerators
+	//   storage: i, d and h families with local instance storage
+	Preset string
+	// MaxLaunchFailureRate restricts nodes to instance types whose launches
+	// fail for lack of capacity no more often than this fraction in at least
+	// one zone, as reported by the cloud provider's capacity signal. Zero means
+	// unconstrained.
+	MaxLaunchFailureRate float64
+	// RotationInterval is how often nodes are replaced, e.g. to apply security
+	// patches. If set, instance types that spend more than 1% of the interval
+	// launching, as reported by the cloud provider, are ranked last.
+	RotationInterval time.Duration
+	// RequireEFA restricts nodes to instance types that support Elastic Fabric
+	// Adapters, e.g. for HPC or distributed training. MinEFAInterfaces also
+	// requires at least this many EFA interfaces, and implies RequireEFA. Zero
+	// means unconstrained.
+	RequireEFA       bool
+	MinEFAInterfaces int64
+	// RequireIPv6 restricts nodes to instance types that support IPv6, e.g.
+	// for subnets of IPv6-only VPCs, in addition to being offered in one of
+	// the subnets' zones.
+	RequireIPv6 bool
+	// RequireJumboFrames restricts nodes to instance types that support 9001
+	// MTU. Jumbo frames only apply within a VPC or peered VPCs; traffic through
+	// internet, VPN or transit gateways is limited to 1500 MTU, and cluster
+	// placement groups don't change which instance types support them.
+	RequireJumboFrames bool
+	// MinNetworkBandwidthGbps restricts nodes to instance types whose baseline
+	// network bandwidth is at least this value. Instance types that only burst
+	// to a bandwidth, e.g. "Up to 10 Gigabit", don't guarantee any. Zero means
+	// unconstrained.
+	MinNetworkBandwidthGbps float64
+	// RequireMetal restricts nodes to bare metal instance types, e.g. for
+	// nested virtualization or per-socket licensing. Bare metal instance types
+	// are otherwise excluded unless named in InstanceTypes. If there are pods,
+	// instance types that fit them most efficiently are ordered first.
+	RequireMetal bool
+	// RequireFPGA restricts nodes to instance types with FPGAs, e.g. f1 for
+	// hardware accelerated pipelines, which are otherwise excluded unless
+	// named in InstanceTypes. MinFPGAs restricts nodes to instance types with
+	// at least this many FPGAs, and implies RequireFPGA. Zero means
+	// unconstrained.
+	RequireFPGA bool
+	MinFPGAs    int64
+	// IncludeMetal adds bare metal instance types to the default instance
+	// types, alongside virtualized ones, without requiring them.
+	IncludeMetal bool
+	// Tenancy launches nodes with the tenancy, one of default, dedicated or
+	// host, restricting them to instance types that support it. Host requires
+	// support for dedicated hosts. EC2 doesn't report which instance types
+	// support dedicated instances, so dedicated doesn't restrict them. Unset
+	// means default, which every instance type supports.
+	Tenancy string
+	// BootMode restricts nodes to instance types that support the boot mode,
+	// either uefi or legacy-bios, which must match the boot mode of the image,
+	// e.g. uefi for images that only boot with UEFI. Unset means any boot mode.
+	BootMode string
+	// RequireNitro restricts nodes to instance types built on the Nitro
+	// System, e.g. for EBS or instance metadata features that Xen instance
+	// types don't support. Bare metal instance types report no hypervisor, but
+	// are built on the Nitro System too.
+	RequireNitro bool
+	// ExcludeBurstable restricts nodes to instance types with fixed
+	// performance, e.g. for sustained CPU workloads that would be throttled
+	// once a burstable instance type's CPU credits are spent.
+	// PreferBurstable orders burstable instance types first without excluding
+	// others, e.g. for workloads that are mostly idle.
+	ExcludeBurstable bool
+	PreferBurstable  bool
+	// CurrentGenerationOnly restricts nodes to current generation instance
+	// types, excluding previous generation families like m4 and c4. Unlike
+	// other constraints, it is applied when instance types are discovered.
+	CurrentGenerationOnly bool
+	// InstanceTypeFilters are passed to the cloud provider when instance types
+	// are discovered, keyed by filter name, e.g. hypervisor for EC2. They're
+	// applied in addition to the cloud provider's own filters.
+	InstanceTypeFilters map[string][]string
+	// ExcludedInstanceTypes and ExcludedFamilies exclude instance types from
+	// nodes even if they're default or explicitly allowed by InstanceTypes.
+	// Families are matched by prefix, e.g. t3 excludes both t3 and t3a.
+	ExcludedInstanceTypes []string
+	ExcludedFamilies      []string
+	// RequireLocalStorage restricts nodes to instance types with instance store
+	// volumes, e.g. NVMe disks for databases.
+	RequireLocalStorage bool
+	// MinLocalStorageGB restricts nodes to instance types whose instance store
+	// volumes total at least this many GB. Zero means unconstrained.
+	MinLocalStorageGB int64
+	// MinGenerations restricts nodes to instance types whose family generation,
+	// e.g. 6 for m6i or c6g, is at least the minimum for their architecture,
+	// keyed by amd64 or arm64. For example, arm64: 7 requires Graviton3 or
+	// newer while amd64: 6 requires 6th generation Intel or AMD families.
+	MinGenerations map[string]int
+	// Commitments describe the instance types covered by savings plans or
+	// reserved instances. Covered instance types are ordered first.
+	Commitments *CommitmentCoverage
+	// Group composes further constraints with AND and OR semantics. Nodes
+	// must satisfy both the group and the constraints above.
+	Group *ConstraintGroup
+}
+
+// CommitmentCoverage describes the instance types that savings plans or
+// reserved instances discount
+type CommitmentCoverage struct {
+	// InstanceFamilies and InstanceTypes that are covered, e.g. m5 and c5.large
+	InstanceFamilies []string
+	InstanceTypes    []string
+	// CapacityTypes the commitments apply to. Defaults to on-demand.
+	CapacityTypes []string
+	// Required restricts nodes to covered instance types when the capacity
+	// type is covered, rather than only ordering them first
+	Required bool
+}
+
+// ConstraintGroup is satisfied if its Constraints, every group in AllOf, and,
+// if there are any, at least one group in AnyOf are satisfied. Constraints
+// within a group only select instance types; their Pods are ignored.
+type ConstraintGroup struct {
+	Constraints *Constraints
+	AllOf       []ConstraintGroup
+	AnyOf       []ConstraintGroup
 }
 
 // Packing is a solution to packing pods onto nodes given constraints.
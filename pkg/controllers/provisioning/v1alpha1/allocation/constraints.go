@@ -25,6 +25,7 @@ import (
 	"github.com/mitchellh/hashstructure/v2"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -87,5 +88,7 @@ func (c *Constraints) getNodeOverhead(ctx context.Context, node *v1.Node) (v1.Re
 			pods = append(pods, &v1.Pod{Spec: daemonSet.Spec.Template.Spec})
 		}
 	}
-	return resources.RequestsForPods(pods...), nil
+	overhead := resources.RequestsForPods(pods...)
+	overhead[v1.ResourcePods] = *resource.NewQuantity(int64(len(pods)), resource.DecimalSI)
+	return overhead, nil
 }
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packing
+
+import (
+	"github.com/awslabs/karpenter/pkg/utils/resources"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var packingEfficiency = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "karpenter",
+		Subsystem: "packing",
+		Name:      "efficiency",
+		Help:      "Fraction of the instance type's allocatable cpu and memory requested by the most recent packing of pods onto it.",
+	},
+	[]string{"instance_type"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(packingEfficiency)
+}
+
+// Efficiency returns the fraction of the instance type's allocatable cpu and
+// memory, after its reserved resources, that is requested by the pods.
+func Efficiency(instance *Instance, pods []*v1.Pod) float64 {
+	total := nodeCapacityFrom(instance).total
+	overhead := reservedFor(instance, total)
+	requests := resources.RequestsForPods(pods...)
+	return (fractionOf(requests.Cpu(), total.Cpu(), overhead.Cpu()) +
+		fractionOf(requests.Memory(), total.Memory(), overhead.Memory())) / 2
+}
+
+func fractionOf(requested *resource.Quantity, total *resource.Quantity, overhead *resource.Quantity) float64 {
+	allocatable := total.MilliValue() - overhead.MilliValue()
+	if allocatable <= 0 {
+		return 0
+	}
+	return float64(requested.MilliValue()) / float64(allocatable)
+}
@@ -0,0 +1,35 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packing
+
+import (
+	"github.com/awslabs/karpenter/pkg/utils/resources"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Headroom returns the resources of the instance type that remain for workload
+// pods after the resources reserved for system daemons and the kubelet, and
+// the overhead, e.g. of daemonsets. Resources are negative if the overhead
+// doesn't fit.
+func Headroom(instance *Instance, overhead v1.ResourceList) v1.ResourceList {
+	total := nodeCapacityFrom(instance).total
+	headroom := total.DeepCopy()
+	for resourceName, quantity := range resources.Merge(reservedFor(instance, total), overhead) {
+		remaining := headroom[resourceName]
+		remaining.Sub(quantity)
+		headroom[resourceName] = remaining
+	}
+	return headroom
+}
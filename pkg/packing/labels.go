@@ -0,0 +1,84 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/awslabs/karpenter/pkg/apis/provisioning/v1alpha1"
+)
+
+var (
+	// Well known labels derived from the instance type, applied to nodes
+	InstanceFamilyLabelKey             = "node.k8s.aws/instance-family"
+	InstanceSizeLabelKey               = "node.k8s.aws/instance-size"
+	InstanceHypervisorLabelKey         = "node.k8s.aws/instance-hypervisor"
+	InstanceGPUManufacturerLabelKey    = "node.k8s.aws/instance-gpu-manufacturer"
+	InstanceGPUNameLabelKey            = "node.k8s.aws/instance-gpu-name"
+	InstanceGPUCountLabelKey           = "node.k8s.aws/instance-gpu-count"
+	InstanceNetworkPerformanceLabelKey = "node.k8s.aws/instance-network-performance"
+	InstanceLocalStorageLabelKey       = "node.k8s.aws/instance-local-storage"
+
+	invalidLabelValueCharacters = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// Labels returns the well known labels describing the instance type. Values
+// are sanitized so that they are valid label values.
+func (i *Instance) Labels() map[string]string {
+	labels := map[string]string{}
+	if i.InstanceType != nil {
+		parts := strings.SplitN(*i.InstanceType, ".", 2)
+		labels[InstanceFamilyLabelKey] = parts[0]
+		if len(parts) == 2 {
+			labels[InstanceSizeLabelKey] = parts[1]
+		}
+	}
+	if i.Hypervisor != nil {
+		labels[InstanceHypervisorLabelKey] = *i.Hypervisor
+	}
+	if i.GpuInfo != nil && len(i.GpuInfo.Gpus) > 0 {
+		if gpu := i.GpuInfo.Gpus[0]; gpu != nil {
+			if gpu.Manufacturer != nil {
+				labels[InstanceGPUManufacturerLabelKey] = *gpu.Manufacturer
+			}
+			if gpu.Name != nil {
+				labels[InstanceGPUNameLabelKey] = *gpu.Name
+			}
+		}
+		labels[InstanceGPUCountLabelKey] = fmt.Sprint(CountNvidiaGPUs(i) + CountAMDGPUs(i))
+	}
+	if i.NetworkInfo != nil && i.NetworkInfo.NetworkPerformance != nil {
+		labels[InstanceNetworkPerformanceLabelKey] = *i.NetworkInfo.NetworkPerformance
+	}
+	labels[InstanceLocalStorageLabelKey] = fmt.Sprint(i.InstanceStorageSupported != nil && *i.InstanceStorageSupported)
+	if i.Architecture != "" {
+		labels[v1alpha1.ArchitectureLabelKey] = i.Architecture
+	}
+	for key, value := range labels {
+		labels[key] = labelValueFor(value)
+	}
+	return labels
+}
+
+// labelValueFor replaces characters that aren't allowed in label values, e.g. "Up to 10 Gigabit" becomes "Up-to-10-Gigabit"
+func labelValueFor(value string) string {
+	value = invalidLabelValueCharacters.ReplaceAllString(value, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "-_.")
+}
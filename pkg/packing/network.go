@@ -0,0 +1,68 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packing
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// networkPerformancePattern matches EC2's network performance descriptions,
+// e.g. "Up to 25 Gigabit", "100 Gigabit" or "4x 100 Gigabit"
+var networkPerformancePattern = regexp.MustCompile(`^(Up to )?(?:(\d+)x )?(\d+(?:\.\d+)?) (Gigabit|Megabit)$`)
+
+// ParseNetworkPerformance normalizes EC2's network performance description into
+// the baseline and burst bandwidth in Gbps. Instance types described as "Up to"
+// a bandwidth only burst to it, so their baseline is zero. Qualitative
+// descriptions of previous generation instance types, e.g. "Moderate", can't be
+// parsed.
+func ParseNetworkPerformance(networkPerformance string) (baselineGbps float64, burstGbps float64, err error) {
+	matches := networkPerformancePattern.FindStringSubmatch(networkPerformance)
+	if matches == nil {
+		return 0, 0, fmt.Errorf("unrecognized network performance %q", networkPerformance)
+	}
+	burstGbps, err = strconv.ParseFloat(matches[3], 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing network performance %q, %w", networkPerformance, err)
+	}
+	if matches[2] != "" {
+		cards, err := strconv.ParseFloat(matches[2], 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parsing network performance %q, %w", networkPerformance, err)
+		}
+		burstGbps *= cards
+	}
+	if matches[4] == "Megabit" {
+		burstGbps /= 1000
+	}
+	if matches[1] != "" {
+		return 0, burstGbps, nil
+	}
+	return burstGbps, burstGbps, nil
+}
+
+// NetworkBandwidthGbps returns the instance type's baseline and burst network
+// bandwidth, or zero if the network performance is unknown
+func (i *Instance) NetworkBandwidthGbps() (baselineGbps float64, burstGbps float64) {
+	if i.NetworkInfo == nil || i.NetworkInfo.NetworkPerformance == nil {
+		return 0, 0
+	}
+	baselineGbps, burstGbps, err := ParseNetworkPerformance(*i.NetworkInfo.NetworkPerformance)
+	if err != nil {
+		return 0, 0
+	}
+	return baselineGbps, burstGbps
+}
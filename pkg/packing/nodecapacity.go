@@ -17,6 +17,7 @@ package packing
 import (
 	"fmt"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/awslabs/karpenter/pkg/utils/resources"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -28,19 +29,69 @@ type nodeCapacity struct {
 	total        v1.ResourceList
 }
 
+const (
+	// addressesPerPrefix is the number of IPv4 addresses in each /28 prefix
+	// assigned to a network interface when prefix delegation is enabled
+	addressesPerPrefix = 16
+	// maxPodsWithPrefixDelegation caps the pods on instance types with fewer
+	// than smallInstanceVCPUs vCPUs, and largeMaxPodsWithPrefixDelegation on
+	// larger instance types, as recommended by the EKS max pods calculator
+	maxPodsWithPrefixDelegation      = 110
+	largeMaxPodsWithPrefixDelegation = 250
+	smallInstanceVCPUs               = 30
+)
+
+// MaxPods returns the number of pods the VPC CNI can assign IPv4 addresses to
+// on the instance type, which is calculated using the formula:
+// max number of ENIs * (IPv4 Addresses per ENI -1) + 2
+// https://github.com/awslabs/amazon-eks-ami/blob/master/files/eni-max-pods.txt#L20
+// With PrefixDelegation, each address slot is a prefix of 16 addresses instead,
+// capped at 110 pods, or 250 pods on instance types with at least 30 vCPUs.
+func (i *Instance) MaxPods() int64 {
+	if i.NetworkInfo == nil {
+		return 0
+	}
+	addresses := aws.Int64Value(i.NetworkInfo.MaximumNetworkInterfaces) * (aws.Int64Value(i.NetworkInfo.Ipv4AddressesPerInterface) - 1)
+	if !i.PrefixDelegation {
+		return addresses + 2
+	}
+	maxPods := int64(maxPodsWithPrefixDelegation)
+	if i.VCpuInfo != nil && aws.Int64Value(i.VCpuInfo.DefaultVCpus) >= smallInstanceVCPUs {
+		maxPods = largeMaxPodsWithPrefixDelegation
+	}
+	if pods := addresses*addressesPerPrefix + 2; pods < maxPods {
+		return pods
+	}
+	return maxPods
+}
+
+// VCPUsOf returns the instance type's vCPUs, or zero if its info is incomplete
+func VCPUsOf(instanceType *Instance) int64 {
+	if instanceType.VCpuInfo == nil {
+		return 0
+	}
+	return aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus)
+}
+
+// MemoryMiBOf returns the instance type's memory, or zero if its info is incomplete
+func MemoryMiBOf(instanceType *Instance) int64 {
+	if instanceType.MemoryInfo == nil {
+		return 0
+	}
+	return aws.Int64Value(instanceType.MemoryInfo.SizeInMiB)
+}
+
+// nodeCapacityFrom returns the capacity of the instance type, which has no CPU or memory if its info is incomplete
 func nodeCapacityFrom(instanceType *Instance) *nodeCapacity {
-	// The number of pods per node is calculated using the formula:
-	// max number of ENIs * (IPv4 Addresses per ENI -1) + 2
-	// https://github.com/awslabs/amazon-eks-ami/blob/master/files/eni-max-pods.txt#L20
-	podResources := *instanceType.NetworkInfo.MaximumNetworkInterfaces*(*instanceType.NetworkInfo.Ipv4AddressesPerInterface-1) + 2
 	return &nodeCapacity{
 		instanceType: instanceType,
 		total: v1.ResourceList{
-			v1.ResourceCPU:      resource.MustParse(fmt.Sprint(*instanceType.VCpuInfo.DefaultVCpus)),
-			v1.ResourceMemory:   resource.MustParse(fmt.Sprintf("%dMi", *instanceType.MemoryInfo.SizeInMiB)),
-			resources.NvidiaGPU: resource.MustParse(fmt.Sprint(countNvidiaGPUs(instanceType))),
-			resources.AWSNeuron: resource.MustParse(fmt.Sprint(countAWSNeurons(instanceType))),
-			v1.ResourcePods:     resource.MustParse(fmt.Sprint(podResources)),
+			v1.ResourceCPU:      resource.MustParse(fmt.Sprint(VCPUsOf(instanceType))),
+			v1.ResourceMemory:   resource.MustParse(fmt.Sprintf("%dMi", MemoryMiBOf(instanceType))),
+			resources.NvidiaGPU: resource.MustParse(fmt.Sprint(CountSchedulableNvidiaGPUs(instanceType))),
+			resources.AMDGPU:    resource.MustParse(fmt.Sprint(CountAMDGPUs(instanceType))),
+			resources.AWSNeuron: resource.MustParse(fmt.Sprint(CountAWSNeurons(instanceType))),
+			v1.ResourcePods:     resource.MustParse(fmt.Sprint(instanceType.MaxPods())),
 		},
 	}
 }
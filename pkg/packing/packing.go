@@ -19,9 +19,11 @@ import (
 	"math"
 	"sort"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/awslabs/karpenter/pkg/cloudprovider"
 	"github.com/awslabs/karpenter/pkg/utils/binpacking"
+	"github.com/awslabs/karpenter/pkg/utils/functional"
 	"github.com/awslabs/karpenter/pkg/utils/resources"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
@@ -31,6 +33,66 @@ type Instance struct {
 	// TODO replace w/ generic instance parameters
 	ec2.InstanceTypeInfo
 	Zones []string
+	// CapacityTypes are those the instance type was selected for that it
+	// supports, in order of preference
+	CapacityTypes []string
+	// Price is the hourly price in USD of the most preferred of the
+	// CapacityTypes, or zero if unknown
+	Price float64
+	// InterruptionScore rates how frequently the instance type is interrupted
+	// if its most preferred capacity type is spot, from 0 for the least to 1
+	// for the most frequently interrupted, or zero if it isn't scored
+	InterruptionScore float64
+	// PrefixDelegation is set if the VPC CNI assigns IPv4 prefixes to network
+	// interfaces rather than individual addresses, which raises MaxPods
+	PrefixDelegation bool
+	// Architecture is the architecture nodes of the instance type run as,
+	// e.g. amd64 or arm64, if known
+	Architecture string
+	// ZonalCapacityTypes are the capacity types the instance type is offered
+	// as in each of its Zones, if known, e.g. if spot is only offered in some
+	ZonalCapacityTypes map[string][]string
+	// OnDemandFallback is set if the instance type was selected for on-demand
+	// because no instance types could be selected for the capacity types that
+	// were requested
+	OnDemandFallback bool
+	// GPUReplicas is the number of schedulable replicas each NVIDIA GPU is
+	// advertised as if the GPUs are shared by time-slicing, or zero if not
+	GPUReplicas int64
+	// Rank orders the instance types of a packing before size, from zero for
+	// those the cloud provider prefers most, e.g. instance types in preferred
+	// families. Instance types of equal rank are ordered by size.
+	Rank int
+	// Reserved is the CPU and memory that nodes of the instance type reserve
+	// for system daemons and the kubelet, which pods can't use. If set, it
+	// replaces the kubelet overhead estimated from the node's capacity.
+	Reserved v1.ResourceList
+}
+
+// IsOfferedIn is true if the instance type is offered as the capacity type in
+// the zone. If the zone's capacity types aren't known, the instance type is
+// assumed to be offered as any capacity type it supports.
+func (i *Instance) IsOfferedIn(zone string, capacityType string) bool {
+	capacityTypes, ok := i.ZonalCapacityTypes[zone]
+	return !ok || functional.ContainsString(capacityTypes, capacityType)
+}
+
+// PricePerVCPU returns the hourly price in USD of each of the instance type's
+// vCPUs, or zero if it isn't priced or its vCPUs are unknown
+func (i *Instance) PricePerVCPU() float64 {
+	if vCPUs := VCPUsOf(i); vCPUs > 0 {
+		return i.Price / float64(vCPUs)
+	}
+	return 0
+}
+
+// PricePerGiB returns the hourly price in USD of each GiB of the instance
+// type's memory, or zero if it isn't priced or its memory is unknown
+func (i *Instance) PricePerGiB() float64 {
+	if memoryMiB := MemoryMiBOf(i); memoryMiB > 0 {
+		return i.Price / (float64(memoryMiB) / 1024)
+	}
+	return 0
 }
 
 type packingResult struct {
@@ -59,7 +121,7 @@ func NewPacker() Packer {
 
 // Pack returns the node packings for the provided pods. It computes a set of viable
 // instance types for each packing of pods. Instance variety enables the cloud provider
-// to make better cost and availability decisions. The instance types returned are sorted by resources.
+// to make better cost and availability decisions. The instance types returned are sorted by rank, then resources.
 // Pods provided are all schedulable in the same zone as tightly as possible.
 // It follows the First Fit Decreasing bin packing technique, reference-
 // https://en.wikipedia.org/wiki/Bin_packing_problem#First_Fit_Decreasing_(FFD)
@@ -86,6 +148,7 @@ func (p *packer) Pack(ctx context.Context, pods []*v1.Pod, instanceTypes []*Inst
 			instanceTypeNames = append(instanceTypeNames, *it.InstanceType)
 		}
 		zap.S().Debugf("Selected %d instance type options for %d pod(s) %v", len(packing.InstanceTypes), len(packing.Pods), instanceTypeNames)
+		packingEfficiency.WithLabelValues(instanceTypeNames[0]).Set(Efficiency(packing.InstanceTypes[0], packing.Pods))
 	}
 	return packings
 }
@@ -94,8 +157,7 @@ func (*packer) getNodeCapacities(instanceTypes []*Instance, constraints *cloudpr
 	nodeCapacities := []*nodeCapacity{}
 	for _, instanceType := range instanceTypes {
 		nc := nodeCapacityFrom(instanceType)
-		kubeletOverhead := binpacking.CalculateKubeletOverhead(nc.total)
-		if ok := nc.reserve(resources.Merge(constraints.Overhead, kubeletOverhead)); !ok {
+		if ok := nc.reserve(resources.Merge(constraints.Overhead, reservedFor(instanceType, nc.total))); !ok {
 			zap.S().Infof("Excluding instance type %s because there are not enough resources for the kubelet overhead", nc.instanceType)
 			continue
 		}
@@ -104,6 +166,15 @@ func (*packer) getNodeCapacities(instanceTypes []*Instance, constraints *cloudpr
 	return nodeCapacities
 }
 
+// reservedFor returns the resources the instance type reserves for system
+// daemons and the kubelet, estimating the kubelet overhead if it isn't known
+func reservedFor(instance *Instance, total v1.ResourceList) v1.ResourceList {
+	if instance.Reserved != nil {
+		return instance.Reserved
+	}
+	return binpacking.CalculateKubeletOverhead(total)
+}
+
 // packWithLargestPod will try to pack max number of pods with largest pod in
 // pods across all available node capacities. It returns Packing: max pod count
 // that fit; with their node capacities and list of leftover pods
@@ -113,7 +184,7 @@ func (p *packer) packWithLargestPod(unpackedPods []*v1.Pod, nodeCapacities []*no
 	remainingPods := unpackedPods
 	for _, nc := range nodeCapacities {
 		// check how many pods we can fit with the available capacity
-		result := p.packPodsForCapacity(nc, unpackedPods)
+		result := p.packPodsForCapacity(nc.Copy(), unpackedPods)
 		if len(result.packed) == 0 {
 			continue
 		}
@@ -176,17 +247,38 @@ func (*packer) podsMatch(first, second []*v1.Pod) bool {
 	return true
 }
 
-// sortByResources sorts instance type packings by vcpus and memory resources
+// sortByResources orders instances by rank, then by size, then by price so that the cheapest of
+// equally sized instances are first, and then by interruption score. Instances without a price are
+// ordered after those with one.
 func sortByResources(instances []*Instance) {
-	sort.Slice(instances, func(i, j int) bool { return weightOf(instances[i]) < weightOf(instances[j]) })
+	sort.SliceStable(instances, func(i, j int) bool {
+		if instances[i].Rank != instances[j].Rank {
+			return instances[i].Rank < instances[j].Rank
+		}
+		if weightI, weightJ := weightOf(instances[i]), weightOf(instances[j]); weightI != weightJ {
+			return weightI < weightJ
+		}
+		if priceI, priceJ := priceOf(instances[i]), priceOf(instances[j]); priceI != priceJ {
+			return priceI < priceJ
+		}
+		return instances[i].InterruptionScore < instances[j].InterruptionScore
+	})
+}
+
+func priceOf(instance *Instance) float64 {
+	if instance.Price == 0 {
+		return math.Inf(1)
+	}
+	return instance.Price
 }
 
 func weightOf(instance *Instance) float64 {
 	return euclidean(
-		float64(*instance.VCpuInfo.DefaultVCpus),
-		float64(*instance.MemoryInfo.SizeInMiB/1024), // 1 gb = 1 cpu
-		float64(countNvidiaGPUs(instance))*1000,      // Heavily weigh gpus x 1000
-		float64(countAWSNeurons(instance))*1000,      // Heavily weigh neurons x1000
+		float64(VCPUsOf(instance)),
+		float64(MemoryMiBOf(instance)/1024),     // 1 gb = 1 cpu
+		float64(CountNvidiaGPUs(instance))*1000, // Heavily weigh gpus x 1000
+		float64(CountAMDGPUs(instance))*1000,    // Heavily weigh gpus x 1000
+		float64(CountAWSNeurons(instance))*1000, // Heavily weigh neurons x1000
 	)
 }
 
@@ -198,21 +290,45 @@ func euclidean(values ...float64) float64 {
 	return math.Pow(sum, .5)
 }
 
-func countNvidiaGPUs(instance *Instance) int64 {
+// CountNvidiaGPUs returns the number of NVIDIA GPUs attached to the instance type
+func CountNvidiaGPUs(instance *Instance) int64 {
+	return countGPUs(instance, "NVIDIA")
+}
+
+// CountSchedulableNvidiaGPUs returns the number of NVIDIA GPUs that pods can be scheduled to, which is the number
+// attached to the instance type multiplied by their replicas if they're shared by time-slicing
+func CountSchedulableNvidiaGPUs(instance *Instance) int64 {
+	if instance.GPUReplicas > 1 {
+		return CountNvidiaGPUs(instance) * instance.GPUReplicas
+	}
+	return CountNvidiaGPUs(instance)
+}
+
+// CountAMDGPUs returns the number of AMD GPUs attached to the instance type
+func CountAMDGPUs(instance *Instance) int64 {
+	return countGPUs(instance, "AMD")
+}
+
+func countGPUs(instance *Instance, manufacturer string) int64 {
 	count := int64(0)
 	if instance.GpuInfo != nil {
 		for _, gpu := range instance.GpuInfo.Gpus {
-			count += *gpu.Count
+			if gpu.Manufacturer != nil && *gpu.Manufacturer == manufacturer {
+				count += aws.Int64Value(gpu.Count)
+			}
 		}
 	}
 	return count
 }
 
-func countAWSNeurons(instance *Instance) int64 {
+// CountAWSNeurons returns the number of AWS Inferentia accelerators attached to the instance type
+func CountAWSNeurons(instance *Instance) int64 {
 	count := int64(0)
 	if instance.InferenceAcceleratorInfo != nil {
 		for _, accelerator := range instance.InferenceAcceleratorInfo.Accelerators {
-			count += *accelerator.Count
+			if accelerator.Manufacturer != nil && *accelerator.Manufacturer == "AWS" {
+				count += aws.Int64Value(accelerator.Count)
+			}
 		}
 	}
 	return count